@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+// getAchievementsHandler lists every achievement that can be awarded.
+func getAchievementsHandler(l *zerolog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getAchievements").Logger()
+
+		err := writeJSON(w, http.StatusOK, envelope{"achievements": db.Achievements}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getUserAchievementsDatastore interface {
+	GetUserAchievements(userID uuid.UUID) ([]db.UserAchievement, error)
+}
+
+func getUserAchievementsHandler(l *zerolog.Logger, datastore getUserAchievementsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getUserAchievements").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		userID, err := uuid.Parse(params.ByName("user_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("user_id", params.ByName("user_id")).Msg("invalid user ID")
+			errorResponse(w, http.StatusBadRequest, "invalid user ID")
+
+			return
+		}
+
+		achievements, err := datastore.GetUserAchievements(userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get achievements")
+			errorResponse(w, http.StatusInternalServerError, "failed to get achievements")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"achievements": achievements}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
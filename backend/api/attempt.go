@@ -1,23 +1,255 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
 	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
 )
 
-type createAttemptDatastore interface{}
+type createAttemptDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+	GetBoardMirror(boardID uuid.UUID) ([]db.MirrorPair, error)
+	CreateAttempt(a *db.Attempt) error
+	AwardAchievements(a *db.Attempt) ([]db.UserAchievement, error)
+}
+
+func createAttemptHandler(l *zerolog.Logger, datastore createAttemptDatastore, publisher eventPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "createAttempt").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Status   string `json:"status"`
+			Mirrored bool   `json:"mirrored"`
+			Zone     bool   `json:"zone"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		attempt := &db.Attempt{
+			UserID:    userID,
+			ProblemID: problemID,
+			Status:    input.Status,
+			Mirrored:  input.Mirrored,
+			Zone:      input.Zone,
+		}
+
+		if errs := attempt.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate attempt")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		problem, err := datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		if problem.Status != db.ProblemStatusPublished {
+			errorResponse(w, http.StatusConflict, "attempts can only be logged on published problems")
+			return
+		}
+
+		holds, err := datastore.GetProblemHolds(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem holds")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem holds")
+
+			return
+		}
+
+		if attempt.Zone && !slices.ContainsFunc(holds, func(h db.ProblemHold) bool { return h.Type == db.HoldTypeZone }) {
+			failedValidationResponse(w, map[string]string{"zone": "problem has no zone hold"})
+			return
+		}
+
+		if attempt.Mirrored {
+			if !board.Symmetric {
+				errorResponse(w, http.StatusConflict, "board is not symmetric")
+				return
+			}
 
-func createAttemptHandler(_ *zerolog.Logger, _ createAttemptDatastore) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusNotImplemented)
+			pairs, err := datastore.GetBoardMirror(boardID)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to get mirror mapping")
+				errorResponse(w, http.StatusInternalServerError, "failed to get mirror mapping")
+
+				return
+			}
+
+			_, err = db.MirrorProblemHolds(holds, pairs, nil)
+			if err != nil {
+				logger.Error().Err(err).Msg("problem cannot be mirrored")
+				errorResponse(w, http.StatusConflict, "problem uses holds without a mirror")
+
+				return
+			}
+		}
+
+		err = datastore.CreateAttempt(attempt)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to create attempt")
+			errorResponse(w, http.StatusInternalServerError, "failed to create attempt")
+
+			return
+		}
+
+		// The attempt is already logged, so failing to award achievements
+		// shouldn't fail the request
+		achievements, err := datastore.AwardAchievements(attempt)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to award achievements")
+
+			achievements = []db.UserAchievement{}
+		}
+
+		publisher.Publish(events.Event{Type: events.AttemptLogged, BoardID: boardID, Data: attempt})
+
+		if attempt.Status == db.AttemptStatusSent {
+			publisher.Publish(events.Event{Type: events.ProblemSent, BoardID: boardID, Data: attempt})
+		}
+
+		err = writeJSON(w, http.StatusCreated, envelope{"attempt": attempt, "achievements": achievements}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
 	}
 }
 
-type getAttemptDatastore interface{}
+type getAttemptDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetAttempts(problemID uuid.UUID, userID *uuid.UUID) ([]db.Attempt, error)
+}
+
+func getAttemptHandler(l *zerolog.Logger, datastore getAttemptDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getAttempt").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		var userID *uuid.UUID
+
+		if idStr := r.URL.Query().Get("user_id"); idStr != "" {
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				logger.Error().Err(err).Str("user_id", idStr).Msg("invalid user ID")
+				errorResponse(w, http.StatusBadRequest, "invalid user ID")
+
+				return
+			}
+
+			userID = &id
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		attempts, err := datastore.GetAttempts(problemID, userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get attempts")
+			errorResponse(w, http.StatusInternalServerError, "failed to get attempts")
+
+			return
+		}
 
-func getAttemptHandler(_ *zerolog.Logger, _ getAttemptDatastore) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusNotImplemented)
+		err = writeJSON(w, http.StatusOK, envelope{"attempts": attempts}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
 	}
 }
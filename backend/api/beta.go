@@ -0,0 +1,270 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+type createBetaDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+	CreateBeta(beta *db.Beta) error
+}
+
+type getBetasDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetBetas(problemID uuid.UUID) ([]db.Beta, error)
+}
+
+type deleteBetaDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	DeleteBeta(problemID, betaID, userID uuid.UUID) error
+}
+
+func createBetaHandler(l *zerolog.Logger, datastore createBetaDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "createBeta").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		var input struct {
+			Description string `json:"description"`
+			Moves       []struct {
+				Limb   string    `json:"limb"`
+				HoldID uuid.UUID `json:"hold_id"`
+			} `json:"moves"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		beta := &db.Beta{
+			ProblemID:   problemID,
+			UserID:      userID,
+			Description: input.Description,
+		}
+
+		for _, m := range input.Moves {
+			beta.Moves = append(beta.Moves, db.BetaMove{
+				Limb:   db.Limb(m.Limb),
+				HoldID: m.HoldID,
+			})
+		}
+
+		holds, err := datastore.GetProblemHolds(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem holds")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem holds")
+
+			return
+		}
+
+		if errs := beta.Validate(holds); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate beta")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.CreateBeta(beta)
+		if err != nil {
+			if errors.Is(err, db.ErrDuplicateBeta) {
+				logger.Error().Err(err).Msg("duplicate beta")
+				errorResponse(w, http.StatusConflict, "you have already published a beta for this problem")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to create beta")
+			errorResponse(w, http.StatusInternalServerError, "failed to create beta")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusCreated, envelope{"beta": beta}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+func getBetasHandler(l *zerolog.Logger, datastore getBetasDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getBetas").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		betas, err := datastore.GetBetas(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get betas")
+			errorResponse(w, http.StatusInternalServerError, "failed to get betas")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"betas": betas}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+func deleteBetaHandler(l *zerolog.Logger, datastore deleteBetaDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "deleteBeta").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		betaID, err := uuid.Parse(params.ByName("beta_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("beta_id", params.ByName("beta_id")).Msg("invalid beta ID")
+			errorResponse(w, http.StatusBadRequest, "invalid beta ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		err = datastore.DeleteBeta(problemID, betaID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrBetaNotFound):
+				logger.Error().Err(err).Msg("beta not found")
+				errorResponse(w, http.StatusNotFound, "beta not found")
+
+				return
+			case errors.Is(err, db.ErrNotBetaAuthor):
+				logger.Error().Err(err).Msg("not beta author")
+				errorResponse(w, http.StatusForbidden, "only the author can delete a beta")
+
+				return
+			default:
+				logger.Error().Err(err).Msg("failed to delete beta")
+				errorResponse(w, http.StatusInternalServerError, "failed to delete beta")
+
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
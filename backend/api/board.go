@@ -12,10 +12,13 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/rs/zerolog"
 	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/validator"
 )
 
 type createBoardDatastore interface {
 	CreateBoard(ctx context.Context, b *db.Board) error
+	GetOrganizationRole(organizationID, userID uuid.UUID) (db.OrganizationRole, error)
 }
 
 func createBoardHandler(l *zerolog.Logger, datastore createBoardDatastore) http.HandlerFunc {
@@ -23,8 +26,10 @@ func createBoardHandler(l *zerolog.Logger, datastore createBoardDatastore) http.
 		logger := l.With().Str("requestMethod", r.Method).Str("url", r.URL.String()).Logger()
 
 		var input struct {
-			Name  string `json:"name"`
-			Image string `json:"image"`
+			Name           string     `json:"name"`
+			Image          string     `json:"image"`
+			OrganizationID *uuid.UUID `json:"organizationID"`
+			Public         bool       `json:"public"`
 		}
 
 		err := readJSON(w, r, &input)
@@ -43,19 +48,51 @@ func createBoardHandler(l *zerolog.Logger, datastore createBoardDatastore) http.
 			return
 		}
 
+		ownerID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
 		board := &db.Board{
-			Name:  input.Name,
-			Image: imageData,
+			Name:           input.Name,
+			Image:          imageData,
+			OwnerID:        ownerID,
+			OrganizationID: input.OrganizationID,
+			Public:         input.Public,
 		}
 
 		errs := board.Validate()
 		if errs != nil {
 			logger.Error().Any("validationErrors", errs).Msg("failed to validate board")
-			errorResponse(w, http.StatusBadRequest, "invalid board")
+			failedValidationResponse(w, errs)
 
 			return
 		}
 
+		// Only an organization's owners can add boards to it
+		if board.OrganizationID != nil {
+			role, err := datastore.GetOrganizationRole(*board.OrganizationID, ownerID)
+			if err != nil {
+				switch {
+				case errors.Is(err, db.ErrOrganizationNotFound), errors.Is(err, db.ErrNotOrganizationMember):
+					failedValidationResponse(w, map[string]string{"organizationID": "must be an organization you belong to"})
+				default:
+					logger.Error().Err(err).Msg("failed to get organization role")
+					errorResponse(w, http.StatusInternalServerError, "the server encountered an error while processing your request")
+				}
+
+				return
+			}
+
+			if role != db.OrganizationRoleOwner {
+				errorResponse(w, http.StatusForbidden, db.ErrNotOrganizationOwner.Error())
+				return
+			}
+		}
+
 		err = datastore.CreateBoard(r.Context(), board)
 		if err != nil {
 			logger.Error().Err(err).Msg("failed to create board")
@@ -121,15 +158,177 @@ func getBoardHandler(l *zerolog.Logger, datastore getBoardDatastore) http.Handle
 	}
 }
 
+type updateBoardDatastore interface {
+	GetBoard(uuid.UUID) (*db.Board, error)
+	IsModerator(userID uuid.UUID) (bool, error)
+	GetOrganizationRole(organizationID, userID uuid.UUID) (db.OrganizationRole, error)
+	UpdateBoardAccess(ctx context.Context, b *db.Board) error
+}
+
+// updateBoardHandler lets a board's owner or a moderator hand the board to
+// someone else, move it into or out of an organization, or make it public or
+// private. Fields left out of the request keep their current values.
+func updateBoardHandler(l *zerolog.Logger, datastore updateBoardDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("requestMethod", r.Method).Str("url", r.URL.String()).Logger()
+		params := httprouter.ParamsFromContext(r.Context())
+		idStr := params.ByName("board_id")
+
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", idStr).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		board, err := datastore.GetBoard(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrBoardNotFound):
+				logger.Error().Err(err).Msg("board not found")
+				notFoundResponse(w)
+			default:
+				logger.Error().Err(err).Msg("failed to get board")
+				errorResponse(w, http.StatusInternalServerError, "the server encountered an error while processing your request")
+			}
+
+			return
+		}
+
+		// Moderators can reassign boards whose owner can't act, like the
+		// placeholder user's
+		moderator, err := datastore.IsModerator(userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to check moderator")
+			errorResponse(w, http.StatusInternalServerError, "the server encountered an error while processing your request")
+
+			return
+		}
+
+		if board.OwnerID != userID && !moderator {
+			errorResponse(w, http.StatusForbidden, "only the board owner or a moderator can change who owns or can see a board")
+			return
+		}
+
+		// Decoding over the current values leaves out fields unchanged, while an
+		// explicit null organizationID takes the board out of its organization
+		input := struct {
+			OwnerID        uuid.UUID  `json:"ownerID"`
+			OrganizationID *uuid.UUID `json:"organizationID"`
+			Public         bool       `json:"public"`
+		}{board.OwnerID, board.OrganizationID, board.Public}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to read JSON")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		v := validator.New()
+		v.Check(input.OwnerID != uuid.Nil && input.OwnerID != defaultUserID, "ownerID", "must be a user")
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		// Only an organization's owners can add boards to it, though moderators
+		// can move boards into any organization that exists
+		moved := input.OrganizationID != nil && (board.OrganizationID == nil || *board.OrganizationID != *input.OrganizationID)
+		if moved {
+			role, err := datastore.GetOrganizationRole(*input.OrganizationID, userID)
+			if errors.Is(err, db.ErrNotOrganizationMember) && moderator {
+				err = nil
+			}
+
+			if err != nil {
+				switch {
+				case errors.Is(err, db.ErrOrganizationNotFound):
+					failedValidationResponse(w, map[string]string{"organizationID": "must be an existing organization"})
+				case errors.Is(err, db.ErrNotOrganizationMember):
+					failedValidationResponse(w, map[string]string{"organizationID": "must be an organization you belong to"})
+				default:
+					logger.Error().Err(err).Msg("failed to get organization role")
+					errorResponse(w, http.StatusInternalServerError, "the server encountered an error while processing your request")
+				}
+
+				return
+			}
+
+			if role != db.OrganizationRoleOwner && !moderator {
+				errorResponse(w, http.StatusForbidden, db.ErrNotOrganizationOwner.Error())
+				return
+			}
+		}
+
+		board.OwnerID = input.OwnerID
+		board.OrganizationID = input.OrganizationID
+		board.Public = input.Public
+
+		err = datastore.UpdateBoardAccess(r.Context(), board)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrBoardNotFound):
+				logger.Error().Err(err).Msg("board not found")
+				notFoundResponse(w)
+			default:
+				logger.Error().Err(err).Msg("failed to update board")
+				errorResponse(w, http.StatusInternalServerError, "unable to update board")
+			}
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"board": board}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write JSON response")
+			errorResponse(w, http.StatusInternalServerError, "the server encountered an error while processing your request")
+
+			return
+		}
+	}
+}
+
 type getAllBoardsDatastore interface {
-	GetAllBoards(ctx context.Context) ([]db.Board, error)
+	GetAllBoards(ctx context.Context, userID uuid.UUID, moderator bool) ([]db.Board, error)
+	IsModerator(userID uuid.UUID) (bool, error)
 }
 
+// getAllBoardsHandler lists the public boards and those the caller owns or can
+// see through their organizations. Site moderators see every board, including
+// hidden ones.
 func getAllBoardsHandler(l *zerolog.Logger, datastore getAllBoardsDatastore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := l.With().Str("requestMethod", r.Method).Str("url", r.URL.String()).Logger()
 
-		boards, err := datastore.GetAllBoards(r.Context())
+		userID, err := readOptionalUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		moderator, err := datastore.IsModerator(userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to check moderator")
+			errorResponse(w, http.StatusInternalServerError, "the server encountered an error while processing your request")
+
+			return
+		}
+
+		boards, err := datastore.GetAllBoards(r.Context(), userID, moderator)
 		if err != nil {
 			logger.Error().Err(err).Msg("failed to get all boards")
 			errorResponse(w, http.StatusInternalServerError, "the server encountered an error while processing your request")
@@ -151,7 +350,7 @@ type createHoldsOnBoardDatastore interface {
 	CreateHolds(boardID uuid.UUID, holds []*db.Hold) error
 }
 
-func createHoldsOnBoardHandler(l *zerolog.Logger, datastore createHoldsOnBoardDatastore) http.HandlerFunc {
+func createHoldsOnBoardHandler(l *zerolog.Logger, datastore createHoldsOnBoardDatastore, publisher eventPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := l.With().Str("requestMethod", r.Method).Str("url", r.URL.String()).Logger()
 
@@ -182,23 +381,26 @@ func createHoldsOnBoardHandler(l *zerolog.Logger, datastore createHoldsOnBoardDa
 
 		var holds []*db.Hold
 
-		for _, h := range input.Holds {
+		v := validator.New()
+
+		for i, h := range input.Holds {
 			hold := &db.Hold{
 				BoardID:  id,
 				Vertices: h.Vertices,
 			}
 
-			// Validate the hold
-			if errs := hold.Validate(); errs != nil {
-				logger.Error().Any("validationErrors", errs).Msg("failed to validate hold")
-				errorResponse(w, http.StatusBadRequest, fmt.Sprintf("invalid hold: %v", errs))
-
-				return
-			}
+			v.AddErrors(fmt.Sprintf("holds[%d]", i), hold.Validate())
 
 			holds = append(holds, hold)
 		}
 
+		if !v.Valid() {
+			logger.Error().Any("validationErrors", v.Errors).Msg("failed to validate holds")
+			failedValidationResponse(w, v.Errors)
+
+			return
+		}
+
 		err = datastore.CreateHolds(id, holds)
 		if err != nil {
 			switch {
@@ -215,6 +417,8 @@ func createHoldsOnBoardHandler(l *zerolog.Logger, datastore createHoldsOnBoardDa
 			}
 		}
 
+		publisher.Publish(events.Event{Type: events.HoldLayoutChanged, BoardID: id, Data: holds})
+
 		err = writeJSON(w, http.StatusCreated, envelope{"holds": holds}, nil)
 		if err != nil {
 			logger.Error().Err(err).Msg("failed to write JSON response")
@@ -266,7 +470,7 @@ type updateHoldsOnBoardDatastore interface {
 	UpdateHolds(boardID uuid.UUID, holds []*db.Hold) error
 }
 
-func updateHoldsOnBoardHandler(l *zerolog.Logger, datastore updateHoldsOnBoardDatastore) http.HandlerFunc {
+func updateHoldsOnBoardHandler(l *zerolog.Logger, datastore updateHoldsOnBoardDatastore, publisher eventPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := l.With().Str("requestMethod", r.Method).Str("url", r.URL.String()).Logger()
 
@@ -298,7 +502,9 @@ func updateHoldsOnBoardHandler(l *zerolog.Logger, datastore updateHoldsOnBoardDa
 
 		var holds []*db.Hold
 
-		for _, h := range input.Holds {
+		v := validator.New()
+
+		for i, h := range input.Holds {
 			hold := &db.Hold{
 				BoardID:  id,
 				Vertices: h.Vertices,
@@ -307,17 +513,18 @@ func updateHoldsOnBoardHandler(l *zerolog.Logger, datastore updateHoldsOnBoardDa
 				hold.ID = *h.ID
 			}
 
-			// Validate the hold
-			if errs := hold.Validate(); errs != nil {
-				logger.Error().Any("validationErrors", errs).Msg("failed to validate hold")
-				errorResponse(w, http.StatusBadRequest, fmt.Sprintf("invalid hold: %v", errs))
-
-				return
-			}
+			v.AddErrors(fmt.Sprintf("holds[%d]", i), hold.Validate())
 
 			holds = append(holds, hold)
 		}
 
+		if !v.Valid() {
+			logger.Error().Any("validationErrors", v.Errors).Msg("failed to validate holds")
+			failedValidationResponse(w, v.Errors)
+
+			return
+		}
+
 		err = datastore.UpdateHolds(id, holds)
 		if err != nil {
 			switch {
@@ -334,6 +541,8 @@ func updateHoldsOnBoardHandler(l *zerolog.Logger, datastore updateHoldsOnBoardDa
 			}
 		}
 
+		publisher.Publish(events.Event{Type: events.HoldLayoutChanged, BoardID: id, Data: holds})
+
 		err = writeJSON(w, http.StatusOK, envelope{"holds": holds}, nil)
 		if err != nil {
 			logger.Error().Err(err).Msg("failed to write JSON response")
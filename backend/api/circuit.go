@@ -0,0 +1,391 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+type circuitInput struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Public      bool        `json:"public"`
+	ProblemIDs  []uuid.UUID `json:"problem_ids"`
+}
+
+type createCircuitDatastore interface {
+	CreateCircuit(c *db.Circuit) error
+}
+
+func createCircuitHandler(l *zerolog.Logger, datastore createCircuitDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "createCircuit").Logger()
+
+		ownerID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input circuitInput
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		circuit := &db.Circuit{
+			Name:        input.Name,
+			Description: input.Description,
+			OwnerID:     ownerID,
+			Public:      input.Public,
+			ProblemIDs:  input.ProblemIDs,
+		}
+
+		if errs := circuit.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate circuit")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.CreateCircuit(circuit)
+		if err != nil {
+			if errors.Is(err, db.ErrUnpublishedProblem) || errors.Is(err, db.ErrPrivateProblem) {
+				failedValidationResponse(w, map[string]string{"problem_ids": err.Error()})
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to create circuit")
+			errorResponse(w, http.StatusInternalServerError, "failed to create circuit")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusCreated, envelope{"circuit": circuit}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getCircuitsDatastore interface {
+	GetCircuits(userID uuid.UUID, ownerID *uuid.UUID) ([]db.Circuit, error)
+}
+
+// getCircuitsHandler lists public circuits along with the caller's private
+// ones. ?owner_id= limits the list to one user's circuits.
+func getCircuitsHandler(l *zerolog.Logger, datastore getCircuitsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getCircuits").Logger()
+
+		userID, err := readOptionalUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var ownerID *uuid.UUID
+
+		if idStr := r.URL.Query().Get("owner_id"); idStr != "" {
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				logger.Error().Err(err).Str("owner_id", idStr).Msg("invalid owner ID")
+				errorResponse(w, http.StatusBadRequest, "invalid owner ID")
+
+				return
+			}
+
+			ownerID = &id
+		}
+
+		circuits, err := datastore.GetCircuits(userID, ownerID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get circuits")
+			errorResponse(w, http.StatusInternalServerError, "failed to get circuits")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"circuits": circuits}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getCircuitDatastore interface {
+	GetCircuit(circuitID uuid.UUID) (*db.Circuit, error)
+	GetCircuitProblems(circuitID, userID uuid.UUID) ([]db.Problem, error)
+	GetCircuitProgress(circuitID, userID uuid.UUID) (map[uuid.UUID]db.CircuitProgress, error)
+	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+}
+
+// getCircuitHandler returns a circuit with each of its problems and the
+// progress of a climber through them, by default the caller. Private circuits
+// are reported as not found to everyone but their owner.
+func getCircuitHandler(l *zerolog.Logger, datastore getCircuitDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getCircuit").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		circuitID, err := uuid.Parse(params.ByName("circuit_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("circuit_id", params.ByName("circuit_id")).Msg("invalid circuit ID")
+			errorResponse(w, http.StatusBadRequest, "invalid circuit ID")
+
+			return
+		}
+
+		userID, err := readOptionalUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		climberID := userID
+
+		if idStr := r.URL.Query().Get("user_id"); idStr != "" {
+			climberID, err = uuid.Parse(idStr)
+			if err != nil {
+				logger.Error().Err(err).Str("user_id", idStr).Msg("invalid user ID")
+				errorResponse(w, http.StatusBadRequest, "invalid user ID")
+
+				return
+			}
+		}
+
+		circuit, err := datastore.GetCircuit(circuitID)
+		if err == nil && !circuit.VisibleTo(userID) {
+			err = db.ErrCircuitNotFound
+		}
+
+		if err != nil {
+			if errors.Is(err, db.ErrCircuitNotFound) {
+				logger.Error().Err(err).Msg("circuit not found")
+				errorResponse(w, http.StatusNotFound, "circuit not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get circuit")
+			errorResponse(w, http.StatusInternalServerError, "failed to get circuit")
+
+			return
+		}
+
+		problems, err := datastore.GetCircuitProblems(circuitID, userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get circuit problems")
+			errorResponse(w, http.StatusInternalServerError, "failed to get circuit problems")
+
+			return
+		}
+
+		progress, err := datastore.GetCircuitProgress(circuitID, climberID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get circuit progress")
+			errorResponse(w, http.StatusInternalServerError, "failed to get circuit progress")
+
+			return
+		}
+
+		type circuitProblem struct {
+			*db.Problem
+			Holds    []db.ProblemHold   `json:"holds"`
+			Progress db.CircuitProgress `json:"progress"`
+		}
+
+		circuitProblems := make([]circuitProblem, len(problems))
+		sent := 0
+
+		for i := range problems {
+			holds, err := datastore.GetProblemHolds(problems[i].ID)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to get problem holds")
+				errorResponse(w, http.StatusInternalServerError, "failed to get problem holds")
+
+				return
+			}
+
+			p := progress[problems[i].ID]
+			if p.Sent {
+				sent++
+			}
+
+			circuitProblems[i] = circuitProblem{
+				Problem:  &problems[i],
+				Holds:    holds,
+				Progress: p,
+			}
+		}
+
+		response := struct {
+			*db.Circuit
+			Problems []circuitProblem `json:"problems"`
+			Sent     int              `json:"sent"`
+			Total    int              `json:"total"`
+		}{
+			Circuit:  circuit,
+			Problems: circuitProblems,
+			Sent:     sent,
+			Total:    len(circuitProblems),
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"circuit": response}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type updateCircuitDatastore interface {
+	UpdateCircuit(c *db.Circuit, userID uuid.UUID) error
+}
+
+func updateCircuitHandler(l *zerolog.Logger, datastore updateCircuitDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "updateCircuit").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		circuitID, err := uuid.Parse(params.ByName("circuit_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("circuit_id", params.ByName("circuit_id")).Msg("invalid circuit ID")
+			errorResponse(w, http.StatusBadRequest, "invalid circuit ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input circuitInput
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		circuit := &db.Circuit{
+			ID:          circuitID,
+			Name:        input.Name,
+			Description: input.Description,
+			Public:      input.Public,
+			ProblemIDs:  input.ProblemIDs,
+		}
+
+		if errs := circuit.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate circuit")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.UpdateCircuit(circuit, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrCircuitNotFound):
+				logger.Error().Err(err).Msg("circuit not found")
+				errorResponse(w, http.StatusNotFound, "circuit not found")
+
+				return
+			case errors.Is(err, db.ErrNotCircuitOwner):
+				logger.Error().Err(err).Msg("not circuit owner")
+				errorResponse(w, http.StatusForbidden, "only the owner can change a circuit")
+
+				return
+			case errors.Is(err, db.ErrUnpublishedProblem), errors.Is(err, db.ErrPrivateProblem):
+				failedValidationResponse(w, map[string]string{"problem_ids": err.Error()})
+				return
+			default:
+				logger.Error().Err(err).Msg("failed to update circuit")
+				errorResponse(w, http.StatusInternalServerError, "failed to update circuit")
+
+				return
+			}
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"circuit": circuit}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type deleteCircuitDatastore interface {
+	DeleteCircuit(circuitID, userID uuid.UUID) error
+}
+
+func deleteCircuitHandler(l *zerolog.Logger, datastore deleteCircuitDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "deleteCircuit").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		circuitID, err := uuid.Parse(params.ByName("circuit_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("circuit_id", params.ByName("circuit_id")).Msg("invalid circuit ID")
+			errorResponse(w, http.StatusBadRequest, "invalid circuit ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		err = datastore.DeleteCircuit(circuitID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrCircuitNotFound):
+				logger.Error().Err(err).Msg("circuit not found")
+				errorResponse(w, http.StatusNotFound, "circuit not found")
+
+				return
+			case errors.Is(err, db.ErrNotCircuitOwner):
+				logger.Error().Err(err).Msg("not circuit owner")
+				errorResponse(w, http.StatusForbidden, "only the owner can delete a circuit")
+
+				return
+			default:
+				logger.Error().Err(err).Msg("failed to delete circuit")
+				errorResponse(w, http.StatusInternalServerError, "failed to delete circuit")
+
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
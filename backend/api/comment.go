@@ -0,0 +1,372 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
+)
+
+type createCommentDatastore interface {
+	CreateComment(boardID uuid.UUID, c *db.Comment) error
+}
+
+type getCommentsDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetComments(problemID uuid.UUID, includeHidden bool) ([]*db.Comment, error)
+	IsModerator(userID uuid.UUID) (bool, error)
+}
+
+type updateCommentDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	UpdateComment(problemID uuid.UUID, c *db.Comment, editorID uuid.UUID) error
+}
+
+type deleteCommentDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	DeleteComment(problemID, commentID, userID uuid.UUID) error
+}
+
+// createCommentHandler posts a comment on a problem, or a reply when the body
+// has a parent_id.
+func createCommentHandler(l *zerolog.Logger, datastore createCommentDatastore, publisher eventPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "createComment").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Body     string     `json:"body"`
+			ParentID *uuid.UUID `json:"parent_id"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		comment := &db.Comment{
+			ProblemID: problemID,
+			ParentID:  input.ParentID,
+			AuthorID:  userID,
+			Body:      input.Body,
+		}
+
+		if errs := comment.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate comment")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.CreateComment(boardID, comment)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrProblemNotFound):
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+			case errors.Is(err, db.ErrParentCommentNotFound):
+				failedValidationResponse(w, map[string]string{"parent_id": "must be a comment on this problem"})
+			case errors.Is(err, db.ErrCommentDeleted):
+				errorResponse(w, http.StatusConflict, "cannot reply to a deleted comment")
+			default:
+				logger.Error().Err(err).Msg("failed to create comment")
+				errorResponse(w, http.StatusInternalServerError, "failed to create comment")
+			}
+
+			return
+		}
+
+		publisher.Publish(events.Event{Type: events.CommentCreated, BoardID: boardID, Data: comment})
+
+		err = writeJSON(w, http.StatusCreated, envelope{"comment": comment}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+func getCommentsHandler(l *zerolog.Logger, datastore getCommentsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getComments").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		userID, err := readOptionalUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		moderator, err := canModerateBoard(datastore, board, userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to check moderator")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		comments, err := datastore.GetComments(problemID, moderator)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get comments")
+			errorResponse(w, http.StatusInternalServerError, "failed to get comments")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"comments": comments}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+func updateCommentHandler(l *zerolog.Logger, datastore updateCommentDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "updateComment").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		commentID, err := uuid.Parse(params.ByName("comment_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("comment_id", params.ByName("comment_id")).Msg("invalid comment ID")
+			errorResponse(w, http.StatusBadRequest, "invalid comment ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Body string `json:"body"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		comment := &db.Comment{ID: commentID, Body: input.Body}
+
+		if errs := comment.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate comment")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		err = datastore.UpdateComment(problemID, comment, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrCommentNotFound):
+				logger.Error().Err(err).Msg("comment not found")
+				errorResponse(w, http.StatusNotFound, "comment not found")
+			case errors.Is(err, db.ErrCommentDeleted):
+				errorResponse(w, http.StatusConflict, err.Error())
+			case errors.Is(err, db.ErrNotCommentAuthor):
+				logger.Error().Err(err).Msg("not comment author")
+				errorResponse(w, http.StatusForbidden, "only the author can edit a comment")
+			default:
+				logger.Error().Err(err).Msg("failed to update comment")
+				errorResponse(w, http.StatusInternalServerError, "failed to update comment")
+			}
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"comment": comment}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+func deleteCommentHandler(l *zerolog.Logger, datastore deleteCommentDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "deleteComment").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		commentID, err := uuid.Parse(params.ByName("comment_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("comment_id", params.ByName("comment_id")).Msg("invalid comment ID")
+			errorResponse(w, http.StatusBadRequest, "invalid comment ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		err = datastore.DeleteComment(problemID, commentID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrCommentNotFound):
+				logger.Error().Err(err).Msg("comment not found")
+				errorResponse(w, http.StatusNotFound, "comment not found")
+			case errors.Is(err, db.ErrNotCommentModerator):
+				logger.Error().Err(err).Msg("not allowed to delete comment")
+				errorResponse(w, http.StatusForbidden, err.Error())
+			default:
+				logger.Error().Err(err).Msg("failed to delete comment")
+				errorResponse(w, http.StatusInternalServerError, "failed to delete comment")
+			}
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
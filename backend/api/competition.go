@@ -0,0 +1,363 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+type createCompetitionDatastore interface {
+	CreateCompetition(c *db.Competition) error
+}
+
+func createCompetitionHandler(l *zerolog.Logger, datastore createCompetitionDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "createCompetition").Logger()
+
+		ownerID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Name       string    `json:"name"`
+			Format     string    `json:"format"`
+			FlashBonus int       `json:"flash_bonus"`
+			StartsAt   time.Time `json:"starts_at"`
+			EndsAt     time.Time `json:"ends_at"`
+			Problems   []struct {
+				ProblemID uuid.UUID `json:"problem_id"`
+				Points    *int      `json:"points"`
+			} `json:"problems"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		competition := &db.Competition{
+			Name:       input.Name,
+			OwnerID:    ownerID,
+			Format:     db.CompetitionFormat(input.Format),
+			FlashBonus: input.FlashBonus,
+			StartsAt:   input.StartsAt,
+			EndsAt:     input.EndsAt,
+			Problems:   make([]db.CompetitionProblem, len(input.Problems)),
+		}
+
+		for i, p := range input.Problems {
+			competition.Problems[i] = db.CompetitionProblem{ProblemID: p.ProblemID, Points: db.DefaultCompetitionPoints}
+			if p.Points != nil {
+				competition.Problems[i].Points = *p.Points
+			}
+		}
+
+		if errs := competition.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate competition")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.CreateCompetition(competition)
+		if err != nil {
+			if errors.Is(err, db.ErrUnpublishedProblem) || errors.Is(err, db.ErrPrivateProblem) {
+				failedValidationResponse(w, map[string]string{"problems": err.Error()})
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to create competition")
+			errorResponse(w, http.StatusInternalServerError, "failed to create competition")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusCreated, envelope{"competition": competition}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getCompetitionsDatastore interface {
+	GetCompetitions() ([]db.Competition, error)
+}
+
+func getCompetitionsHandler(l *zerolog.Logger, datastore getCompetitionsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		logger := l.With().Str("handler", "getCompetitions").Logger()
+
+		competitions, err := datastore.GetCompetitions()
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get competitions")
+			errorResponse(w, http.StatusInternalServerError, "failed to get competitions")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"competitions": competitions}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getCompetitionDatastore interface {
+	GetCompetition(competitionID uuid.UUID) (*db.Competition, error)
+	GetProblemByID(problemID uuid.UUID) (*db.Problem, error)
+	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+	CanAccessBoard(boardID, userID uuid.UUID) (bool, error)
+}
+
+// getCompetitionHandler returns a competition with each of its problems and
+// their holds, so competitors can see where the zones are. Problems on boards
+// the caller can't see are left out.
+func getCompetitionHandler(l *zerolog.Logger, datastore getCompetitionDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getCompetition").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		competitionID, err := uuid.Parse(params.ByName("competition_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("competition_id", params.ByName("competition_id")).Msg("invalid competition ID")
+			errorResponse(w, http.StatusBadRequest, "invalid competition ID")
+
+			return
+		}
+
+		userID, err := readOptionalUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		competition, err := datastore.GetCompetition(competitionID)
+		if err != nil {
+			if errors.Is(err, db.ErrCompetitionNotFound) {
+				logger.Error().Err(err).Msg("competition not found")
+				errorResponse(w, http.StatusNotFound, "competition not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get competition")
+			errorResponse(w, http.StatusInternalServerError, "failed to get competition")
+
+			return
+		}
+
+		type competitionProblem struct {
+			*db.Problem
+			Holds  []db.ProblemHold `json:"holds"`
+			Points int              `json:"points"`
+		}
+
+		problems := make([]competitionProblem, 0, len(competition.Problems))
+
+		for _, p := range competition.Problems {
+			problem, err := datastore.GetProblemByID(p.ProblemID)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to get problem")
+				errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+				return
+			}
+
+			ok, err := datastore.CanAccessBoard(problem.BoardID, userID)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to check board access")
+				errorResponse(w, http.StatusInternalServerError, "failed to check board access")
+
+				return
+			}
+
+			if !ok {
+				continue
+			}
+
+			holds, err := datastore.GetProblemHolds(p.ProblemID)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to get problem holds")
+				errorResponse(w, http.StatusInternalServerError, "failed to get problem holds")
+
+				return
+			}
+
+			problems = append(problems, competitionProblem{Problem: problem, Holds: holds, Points: p.Points})
+		}
+
+		response := struct {
+			*db.Competition
+			Problems []competitionProblem `json:"problems"`
+		}{
+			Competition: competition,
+			Problems:    problems,
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"competition": response}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type registerCompetitorDatastore interface {
+	RegisterCompetitor(competitionID, userID uuid.UUID) error
+	WithdrawCompetitor(competitionID, userID uuid.UUID) error
+}
+
+func registerCompetitorHandler(l *zerolog.Logger, datastore registerCompetitorDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "registerCompetitor").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		competitionID, err := uuid.Parse(params.ByName("competition_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("competition_id", params.ByName("competition_id")).Msg("invalid competition ID")
+			errorResponse(w, http.StatusBadRequest, "invalid competition ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		err = datastore.RegisterCompetitor(competitionID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrCompetitionNotFound):
+				logger.Error().Err(err).Msg("competition not found")
+				errorResponse(w, http.StatusNotFound, "competition not found")
+
+				return
+			case errors.Is(err, db.ErrCompetitionEnded):
+				errorResponse(w, http.StatusConflict, err.Error())
+				return
+			default:
+				logger.Error().Err(err).Msg("failed to register competitor")
+				errorResponse(w, http.StatusInternalServerError, "failed to register competitor")
+
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func withdrawCompetitorHandler(l *zerolog.Logger, datastore registerCompetitorDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "withdrawCompetitor").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		competitionID, err := uuid.Parse(params.ByName("competition_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("competition_id", params.ByName("competition_id")).Msg("invalid competition ID")
+			errorResponse(w, http.StatusBadRequest, "invalid competition ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		err = datastore.WithdrawCompetitor(competitionID, userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to withdraw competitor")
+			errorResponse(w, http.StatusInternalServerError, "failed to withdraw competitor")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type getCompetitionResultsDatastore interface {
+	GetCompetition(competitionID uuid.UUID) (*db.Competition, error)
+	GetCompetitionEntries(competitionID uuid.UUID) ([]uuid.UUID, []db.CompetitionAttempt, error)
+}
+
+// getCompetitionResultsHandler ranks competitors from the attempts logged so
+// far, so results update live while the competition runs.
+func getCompetitionResultsHandler(l *zerolog.Logger, datastore getCompetitionResultsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getCompetitionResults").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		competitionID, err := uuid.Parse(params.ByName("competition_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("competition_id", params.ByName("competition_id")).Msg("invalid competition ID")
+			errorResponse(w, http.StatusBadRequest, "invalid competition ID")
+
+			return
+		}
+
+		competition, err := datastore.GetCompetition(competitionID)
+		if err != nil {
+			if errors.Is(err, db.ErrCompetitionNotFound) {
+				logger.Error().Err(err).Msg("competition not found")
+				errorResponse(w, http.StatusNotFound, "competition not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get competition")
+			errorResponse(w, http.StatusInternalServerError, "failed to get competition")
+
+			return
+		}
+
+		competitors, attempts, err := datastore.GetCompetitionEntries(competitionID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get competition entries")
+			errorResponse(w, http.StatusInternalServerError, "failed to get competition results")
+
+			return
+		}
+
+		results := db.ScoreCompetition(*competition, competitors, attempts)
+
+		now := time.Now()
+
+		err = writeJSON(w, http.StatusOK, envelope{
+			"results": results,
+			"live":    now.After(competition.StartsAt) && now.Before(competition.EndsAt),
+		}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
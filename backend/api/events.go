@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
+)
+
+// eventPublisher is where handlers announce changes to a board once they have
+// been saved.
+type eventPublisher interface {
+	Publish(e events.Event)
+}
+
+type eventSubscriber interface {
+	Subscribe(boardID uuid.UUID) (<-chan events.Event, func())
+}
+
+// keepAliveInterval is how often an idle event stream sends a comment, so
+// proxies don't close it.
+const keepAliveInterval = 15 * time.Second
+
+type getBoardEventsDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+}
+
+// getBoardEventsHandler streams changes on a board as server-sent events until
+// the client goes away or the server shuts down.
+func getBoardEventsHandler(l *zerolog.Logger, datastore getBoardEventsDatastore, subscriber eventSubscriber) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getBoardEvents").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		_, err = datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		rc := http.NewResponseController(w)
+
+		// The stream outlives the server's write timeout
+		err = rc.SetWriteDeadline(time.Time{})
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to clear write deadline")
+			errorResponse(w, http.StatusInternalServerError, "streaming is not supported")
+
+			return
+		}
+
+		stream, unsubscribe := subscriber.Subscribe(boardID)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		err = rc.Flush()
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to flush stream")
+			return
+		}
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case <-keepAlive.C:
+				_, err = fmt.Fprint(w, ": keep-alive\n\n")
+
+			case e, ok := <-stream:
+				if !ok {
+					return
+				}
+
+				var data []byte
+
+				data, err = json.Marshal(e)
+				if err != nil {
+					logger.Error().Err(err).Str("event", string(e.Type)).Msg("failed to marshal event")
+					continue
+				}
+
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			}
+
+			if err == nil {
+				err = rc.Flush()
+			}
+
+			if err != nil {
+				logger.Debug().Err(err).Msg("event stream closed")
+				return
+			}
+		}
+	}
+}
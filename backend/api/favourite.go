@@ -0,0 +1,163 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+type favouriteDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	AddFavourite(userID, problemID uuid.UUID) error
+	RemoveFavourite(userID, problemID uuid.UUID) error
+}
+
+func addFavouriteHandler(l *zerolog.Logger, datastore favouriteDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "addFavourite").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		err = datastore.AddFavourite(userID, problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to add favourite")
+			errorResponse(w, http.StatusInternalServerError, "failed to add favourite")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func removeFavouriteHandler(l *zerolog.Logger, datastore favouriteDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "removeFavourite").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		err = datastore.RemoveFavourite(userID, problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to remove favourite")
+			errorResponse(w, http.StatusInternalServerError, "failed to remove favourite")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type getFavouritesDatastore interface {
+	GetFavourites(userID uuid.UUID) ([]db.Favourite, error)
+}
+
+func getFavouritesHandler(l *zerolog.Logger, datastore getFavouritesDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getFavourites").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		favourites, err := datastore.GetFavourites(userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get favourites")
+			errorResponse(w, http.StatusInternalServerError, "failed to get favourites")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"favourites": favourites}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
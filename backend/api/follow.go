@@ -0,0 +1,192 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type followDatastore interface {
+	FollowUser(followerID, followeeID uuid.UUID) error
+	UnfollowUser(followerID, followeeID uuid.UUID) error
+}
+
+func followUserHandler(l *zerolog.Logger, datastore followDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "followUser").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		followeeID, err := uuid.Parse(params.ByName("user_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("user_id", params.ByName("user_id")).Msg("invalid user ID")
+			errorResponse(w, http.StatusBadRequest, "invalid user ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		err = datastore.FollowUser(userID, followeeID)
+		if err != nil {
+			if errors.Is(err, db.ErrSelfFollow) {
+				errorResponse(w, http.StatusConflict, err.Error())
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to follow user")
+			errorResponse(w, http.StatusInternalServerError, "failed to follow user")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func unfollowUserHandler(l *zerolog.Logger, datastore followDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "unfollowUser").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		followeeID, err := uuid.Parse(params.ByName("user_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("user_id", params.ByName("user_id")).Msg("invalid user ID")
+			errorResponse(w, http.StatusBadRequest, "invalid user ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		err = datastore.UnfollowUser(userID, followeeID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to unfollow user")
+			errorResponse(w, http.StatusInternalServerError, "failed to unfollow user")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type getFollowsDatastore interface {
+	GetFollowing(userID uuid.UUID) ([]db.Follow, error)
+	GetFollowers(userID uuid.UUID) ([]db.Follow, error)
+}
+
+// getFollowsHandler lists who a user follows, or with followers set, who
+// follows them.
+func getFollowsHandler(l *zerolog.Logger, datastore getFollowsDatastore, followers bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getFollows").Bool("followers", followers).Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		userID, err := uuid.Parse(params.ByName("user_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("user_id", params.ByName("user_id")).Msg("invalid user ID")
+			errorResponse(w, http.StatusBadRequest, "invalid user ID")
+
+			return
+		}
+
+		get, key := datastore.GetFollowing, "following"
+		if followers {
+			get, key = datastore.GetFollowers, "followers"
+		}
+
+		follows, err := get(userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get follows")
+			errorResponse(w, http.StatusInternalServerError, "failed to get "+key)
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{key: follows}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getFeedDatastore interface {
+	GetFeed(userID uuid.UUID, cursor *db.FeedCursor, limit int) ([]db.FeedItem, *db.FeedCursor, error)
+}
+
+// getFeedHandler pages through what the users the caller follows have been
+// up to, newest first. Pass the next_cursor from one page as ?cursor= to get
+// the next.
+func getFeedHandler(l *zerolog.Logger, datastore getFeedDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getFeed").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		qs := r.URL.Query()
+		v := validator.New()
+
+		limit := readInt(qs, "limit", 20, v)
+		v.Check(limit > 0 && limit <= 100, "limit", "must be between 1 and 100")
+
+		var cursor *db.FeedCursor
+
+		if s := qs.Get("cursor"); s != "" {
+			cursor, err = db.ParseFeedCursor(s)
+			v.Check(err == nil, "cursor", "must be a cursor from a previous page")
+		}
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		items, next, err := datastore.GetFeed(userID, cursor, limit)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get feed")
+			errorResponse(w, http.StatusInternalServerError, "failed to get feed")
+
+			return
+		}
+
+		var nextCursor *string
+
+		if next != nil {
+			s := next.String()
+			nextCursor = &s
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"feed": items, "next_cursor": nextCursor}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
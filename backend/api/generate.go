@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+// generateAttempts is how many random problems are tried before giving up on
+// finding one that is valid and not already set.
+const generateAttempts = 20
+
+type generateProblemDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetHolds(boardID uuid.UUID) ([]db.Hold, error)
+	FindSimilarProblems(boardID, problemID uuid.UUID, holds []db.ProblemHold, minSimilarity float64, limit int) ([]db.SimilarProblem, error)
+	CreateProblem(boardID uuid.UUID, problem *db.Problem, holds []db.ProblemHold) error
+}
+
+// generateProblemHandler makes up a random problem on a board that isn't a
+// near-duplicate of any existing problem. It is returned unsaved unless the
+// request asks for it to be saved as a draft.
+func generateProblemHandler(l *zerolog.Logger, datastore generateProblemDatastore, publisher eventPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "generateProblem").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		setterID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Name  string   `json:"name"`
+			Grade *int     `json:"grade"`
+			Reach *float64 `json:"reach"`
+			Seed  *uint64  `json:"seed"`
+			Save  bool     `json:"save"`
+		}
+
+		if r.ContentLength != 0 {
+			err = readJSON(w, r, &input)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to decode request body")
+				errorResponse(w, http.StatusBadRequest, err.Error())
+
+				return
+			}
+		}
+
+		v := validator.New()
+
+		grade := 3
+		if input.Grade != nil {
+			grade = *input.Grade
+			v.Check(grade >= 0 && grade <= db.MaxGrade, "grade", fmt.Sprintf("must be between 0 and %d", db.MaxGrade))
+		}
+
+		opts := db.DefaultGenerateOptions(grade)
+		if input.Reach != nil {
+			opts.Reach = *input.Reach
+			v.Check(opts.Reach > 0 && opts.Reach <= 1, "reach", "must be greater than 0 and at most 1")
+		}
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		_, err = datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		boardHolds, err := datastore.GetHolds(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get holds")
+			errorResponse(w, http.StatusInternalServerError, "unable to get holds")
+
+			return
+		}
+
+		seed := rand.Uint64() //nolint:gosec
+		if input.Seed != nil {
+			seed = *input.Seed
+		}
+
+		rng := rand.New(rand.NewPCG(seed, seed)) //nolint:gosec
+
+		name := input.Name
+		if name == "" {
+			name = fmt.Sprintf("Generated V%d", grade)
+		}
+
+		problem := &db.Problem{
+			ID:       uuid.New(),
+			BoardID:  boardID,
+			Name:     name,
+			SetterID: setterID,
+			Status:   db.ProblemStatusDraft,
+			Grade:    &grade,
+		}
+
+		var problemHolds []db.ProblemHold
+
+		for range generateAttempts {
+			holds, err := db.GenerateProblemHolds(boardHolds, opts, rng)
+			if err != nil || problem.Validate(holds, boardHolds) != nil {
+				continue
+			}
+
+			duplicates, err := datastore.FindSimilarProblems(boardID, uuid.Nil, holds, db.NearDuplicateSimilarity, 1)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to check for duplicate problems")
+				errorResponse(w, http.StatusInternalServerError, "failed to generate problem")
+
+				return
+			}
+
+			if len(duplicates) == 0 {
+				problemHolds = holds
+				break
+			}
+		}
+
+		if problemHolds == nil {
+			errorResponse(w, http.StatusUnprocessableEntity, db.ErrCannotGenerate.Error())
+			return
+		}
+
+		for i := range problemHolds {
+			problemHolds[i].ID = uuid.New()
+			problemHolds[i].ProblemID = problem.ID
+		}
+
+		status := http.StatusOK
+
+		if input.Save {
+			err = datastore.CreateProblem(boardID, problem, problemHolds)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to create problem")
+				errorResponse(w, http.StatusInternalServerError, "failed to create problem")
+
+				return
+			}
+
+			publisher.Publish(events.Event{Type: events.ProblemCreated, BoardID: boardID, Data: problem})
+
+			status = http.StatusCreated
+		}
+
+		response := struct {
+			*db.Problem
+			Holds []db.ProblemHold `json:"holds"`
+			Saved bool             `json:"saved"`
+			Seed  uint64           `json:"seed"`
+		}{
+			Problem: problem,
+			Holds:   problemHolds,
+			Saved:   input.Save,
+			Seed:    seed,
+		}
+
+		err = writeJSON(w, status, envelope{"problem": response}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
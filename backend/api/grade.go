@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/grading"
+)
+
+type gradeModelDatastore interface {
+	GetLatestGradeModel() (*db.GradeModel, error)
+}
+
+// suggestGrade predicts a grade for a problem with the latest model. It returns
+// nil without an error when no model has been trained yet.
+func suggestGrade(datastore gradeModelDatastore, rules db.ProblemRules, holds []db.ProblemHold) (*grading.Prediction, error) {
+	model, err := datastore.GetLatestGradeModel()
+	if err != nil {
+		if errors.Is(err, db.ErrNoGradeModel) {
+			return nil, nil
+		}
+
+		return nil, err //nolint:wrapcheck
+	}
+
+	prediction, err := grading.Predict(model, rules, holds)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return &prediction, nil
+}
+
+type getGradePredictionDatastore interface {
+	gradeModelDatastore
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+}
+
+func getGradePredictionHandler(l *zerolog.Logger, datastore getGradePredictionDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getGradePrediction").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		problem, err := datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		holds, err := datastore.GetProblemHolds(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem holds")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem holds")
+
+			return
+		}
+
+		prediction, err := suggestGrade(datastore, problem.Rules, holds)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to predict grade")
+			errorResponse(w, http.StatusInternalServerError, "failed to predict grade")
+
+			return
+		}
+
+		if prediction == nil {
+			errorResponse(w, http.StatusNotFound, db.ErrNoGradeModel.Error())
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"prediction": prediction}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type getLeaderboardDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetLeaderboard(boardID uuid.UUID, start *time.Time, p db.Pagination) ([]db.LeaderboardEntry, db.Metadata, error)
+}
+
+// getLeaderboardHandler ranks climbers on a board over ?period=, which is one
+// of week, month, season or all-time and defaults to all-time.
+func getLeaderboardHandler(l *zerolog.Logger, datastore getLeaderboardDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getLeaderboard").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		qs := r.URL.Query()
+		v := validator.New()
+
+		period := db.LeaderboardPeriod(qs.Get("period"))
+		if period == "" {
+			period = db.LeaderboardPeriodAllTime
+		}
+
+		v.Check(
+			validator.PermittedValue(period, db.LeaderboardPeriodWeek, db.LeaderboardPeriodMonth, db.LeaderboardPeriodSeason, db.LeaderboardPeriodAllTime),
+			"period", "must be one of week, month, season or all-time",
+		)
+
+		pagination := db.Pagination{
+			Page:     readInt(qs, "page", 1, v),
+			PageSize: readInt(qs, "page_size", 20, v),
+		}
+
+		for key, message := range pagination.Validate() {
+			v.AddError(key, message)
+		}
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		_, err = datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		entries, metadata, err := datastore.GetLeaderboard(boardID, period.Start(time.Now()), pagination)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get leaderboard")
+			errorResponse(w, http.StatusInternalServerError, "failed to get leaderboard")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"leaderboard": entries, "period": period, "metadata": metadata}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
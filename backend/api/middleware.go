@@ -1,7 +1,13 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
 )
 
 func enableCORS(next http.Handler) http.Handler {
@@ -9,7 +15,7 @@ func enableCORS(next http.Handler) http.Handler {
 		// Set CORS headers for all responses
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-Requested-With")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-Requested-With, X-User-ID")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
@@ -34,3 +40,54 @@ func maxBodySize(next http.Handler, maxBytes int64) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+type boardAccessChecker interface {
+	CanAccessBoard(boardID, userID uuid.UUID) (bool, error)
+}
+
+// requireBoardAccess guards a board-scoped route so only users who can see the
+// board get through. Everyone else is told it doesn't exist, so private boards
+// aren't given away. Invalid and unknown board IDs are left to next.
+func requireBoardAccess(l *zerolog.Logger, checker boardAccessChecker, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("middleware", "requireBoardAccess").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		userID, err := readOptionalUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		ok, err := checker.CanAccessBoard(boardID, userID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				next(w, r)
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to check board access")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		if !ok {
+			logger.Error().Str("board_id", boardID.String()).Msg("board not accessible")
+			errorResponse(w, http.StatusNotFound, "board not found")
+
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,364 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type getBoardMirrorDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetBoardMirror(boardID uuid.UUID) ([]db.MirrorPair, error)
+}
+
+type setBoardMirrorDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetHolds(boardID uuid.UUID) ([]db.Hold, error)
+	SetBoardMirror(boardID uuid.UUID, pairs []db.MirrorPair) error
+}
+
+type clearBoardMirrorDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	ClearBoardMirror(boardID uuid.UUID) error
+}
+
+type getMirroredProblemDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+	GetHolds(boardID uuid.UUID) ([]db.Hold, error)
+	GetBoardMirror(boardID uuid.UUID) ([]db.MirrorPair, error)
+}
+
+func getBoardMirrorHandler(l *zerolog.Logger, datastore getBoardMirrorDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getBoardMirror").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		pairs, err := datastore.GetBoardMirror(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get mirror mapping")
+			errorResponse(w, http.StatusInternalServerError, "failed to get mirror mapping")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"symmetric": board.Symmetric, "pairs": pairs}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+// setBoardMirrorHandler declares a board symmetric. The mapping is either
+// computed from hold centroids, when a tolerance is given, or taken as is
+// from explicit pairs.
+func setBoardMirrorHandler(l *zerolog.Logger, datastore setBoardMirrorDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "setBoardMirror").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		if board.OwnerID != userID {
+			errorResponse(w, http.StatusForbidden, "only the board owner can change its symmetry")
+			return
+		}
+
+		var input struct {
+			Tolerance *float64        `json:"tolerance"`
+			Pairs     []db.MirrorPair `json:"pairs"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		v := validator.New()
+		v.Check((input.Tolerance == nil) != (input.Pairs == nil), "tolerance", "exactly one of tolerance or pairs must be provided")
+
+		if input.Tolerance != nil {
+			v.Check(*input.Tolerance > 0 && *input.Tolerance <= 0.5, "tolerance", "must be greater than 0 and at most 0.5")
+		}
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		holds, err := datastore.GetHolds(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get holds")
+			errorResponse(w, http.StatusInternalServerError, "unable to get holds")
+
+			return
+		}
+
+		pairs := input.Pairs
+
+		var unmatched []uuid.UUID
+
+		if input.Tolerance != nil {
+			pairs, unmatched = db.ComputeMirrorPairs(holds, *input.Tolerance)
+
+			// A board with nothing mirrored isn't symmetric
+			v.Check(len(pairs) > 0, "tolerance", "no holds mirror each other within this tolerance")
+
+			if !v.Valid() {
+				failedValidationResponse(w, v.Errors)
+				return
+			}
+		} else if errs := db.ValidateMirrorPairs(pairs, holds); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate mirror pairs")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.SetBoardMirror(boardID, pairs)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to set mirror mapping")
+			errorResponse(w, http.StatusInternalServerError, "failed to set mirror mapping")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"symmetric": true, "pairs": pairs, "unmatched": unmatched}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+func clearBoardMirrorHandler(l *zerolog.Logger, datastore clearBoardMirrorDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "clearBoardMirror").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		if board.OwnerID != userID {
+			errorResponse(w, http.StatusForbidden, "only the board owner can change its symmetry")
+			return
+		}
+
+		err = datastore.ClearBoardMirror(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to clear mirror mapping")
+			errorResponse(w, http.StatusInternalServerError, "failed to clear mirror mapping")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func getMirroredProblemHandler(l *zerolog.Logger, datastore getMirroredProblemDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getMirroredProblem").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		if !board.Symmetric {
+			errorResponse(w, http.StatusConflict, "board is not symmetric")
+			return
+		}
+
+		problem, err := datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		holds, err := datastore.GetProblemHolds(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem holds")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem holds")
+
+			return
+		}
+
+		boardHolds, err := datastore.GetHolds(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get holds")
+			errorResponse(w, http.StatusInternalServerError, "unable to get holds")
+
+			return
+		}
+
+		pairs, err := datastore.GetBoardMirror(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get mirror mapping")
+			errorResponse(w, http.StatusInternalServerError, "failed to get mirror mapping")
+
+			return
+		}
+
+		mirrored, err := db.MirrorProblemHolds(holds, pairs, boardHolds)
+		if err != nil {
+			logger.Error().Err(err).Msg("problem cannot be mirrored")
+			errorResponse(w, http.StatusConflict, "problem uses holds without a mirror")
+
+			return
+		}
+
+		response := struct {
+			*db.Problem
+			Mirrored bool             `json:"mirrored"`
+			Holds    []db.ProblemHold `json:"holds"`
+		}{
+			Problem:  problem,
+			Mirrored: true,
+			Holds:    mirrored,
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"problem": response}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
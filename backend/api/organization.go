@@ -0,0 +1,504 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+type createOrganizationDatastore interface {
+	CreateOrganization(o *db.Organization) error
+}
+
+// createOrganizationHandler creates a gym or household, with the caller as its
+// owner.
+func createOrganizationHandler(l *zerolog.Logger, datastore createOrganizationDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "createOrganization").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Name string `json:"name"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		organization := &db.Organization{Name: input.Name, CreatedBy: userID}
+
+		if errs := organization.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate organization")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.CreateOrganization(organization)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to create organization")
+			errorResponse(w, http.StatusInternalServerError, "failed to create organization")
+
+			return
+		}
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/organization/%s", organization.ID))
+
+		err = writeJSON(w, http.StatusCreated, envelope{"organization": organization}, headers)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getOrganizationsDatastore interface {
+	GetOrganizations(userID uuid.UUID) ([]db.Organization, error)
+}
+
+// getOrganizationsHandler lists the organizations the caller belongs to.
+func getOrganizationsHandler(l *zerolog.Logger, datastore getOrganizationsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getOrganizations").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		organizations, err := datastore.GetOrganizations(userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get organizations")
+			errorResponse(w, http.StatusInternalServerError, "failed to get organizations")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"organizations": organizations}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getOrganizationDatastore interface {
+	GetOrganization(organizationID, userID uuid.UUID) (*db.Organization, error)
+	GetOrganizationMembers(organizationID uuid.UUID) ([]db.OrganizationMember, error)
+}
+
+// getOrganizationHandler returns an organization and its members. Only
+// members can see it.
+func getOrganizationHandler(l *zerolog.Logger, datastore getOrganizationDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getOrganization").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		organizationID, err := uuid.Parse(params.ByName("organization_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("organization_id", params.ByName("organization_id")).Msg("invalid organization ID")
+			errorResponse(w, http.StatusBadRequest, "invalid organization ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		organization, err := datastore.GetOrganization(organizationID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrOrganizationNotFound), errors.Is(err, db.ErrNotOrganizationMember):
+				logger.Error().Err(err).Msg("organization not found")
+				errorResponse(w, http.StatusNotFound, "organization not found")
+			default:
+				logger.Error().Err(err).Msg("failed to get organization")
+				errorResponse(w, http.StatusInternalServerError, "failed to get organization")
+			}
+
+			return
+		}
+
+		members, err := datastore.GetOrganizationMembers(organizationID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get organization members")
+			errorResponse(w, http.StatusInternalServerError, "failed to get organization")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"organization": organization, "members": members}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type removeOrganizationMemberDatastore interface {
+	RemoveOrganizationMember(organizationID, memberID, userID uuid.UUID) error
+}
+
+// removeOrganizationMemberHandler removes someone from an organization, which
+// its owners can do to anyone and members can do to leave.
+func removeOrganizationMemberHandler(l *zerolog.Logger, datastore removeOrganizationMemberDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "removeOrganizationMember").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		organizationID, err := uuid.Parse(params.ByName("organization_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("organization_id", params.ByName("organization_id")).Msg("invalid organization ID")
+			errorResponse(w, http.StatusBadRequest, "invalid organization ID")
+
+			return
+		}
+
+		memberID, err := uuid.Parse(params.ByName("user_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("user_id", params.ByName("user_id")).Msg("invalid user ID")
+			errorResponse(w, http.StatusBadRequest, "invalid user ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		err = datastore.RemoveOrganizationMember(organizationID, memberID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrOrganizationNotFound):
+				logger.Error().Err(err).Msg("organization not found")
+				errorResponse(w, http.StatusNotFound, "organization not found")
+			case errors.Is(err, db.ErrNotOrganizationMember):
+				errorResponse(w, http.StatusNotFound, "member not found")
+			case errors.Is(err, db.ErrNotOrganizationOwner):
+				logger.Error().Err(err).Msg("not an organization owner")
+				errorResponse(w, http.StatusForbidden, err.Error())
+			case errors.Is(err, db.ErrLastOrganizationOwner):
+				errorResponse(w, http.StatusConflict, err.Error())
+			default:
+				logger.Error().Err(err).Msg("failed to remove organization member")
+				errorResponse(w, http.StatusInternalServerError, "failed to remove organization member")
+			}
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type createOrganizationInviteDatastore interface {
+	CreateOrganizationInvite(i *db.OrganizationInvite) error
+}
+
+// createOrganizationInviteHandler invites a user to an organization as a
+// member, or as an owner when the body's role says so.
+func createOrganizationInviteHandler(l *zerolog.Logger, datastore createOrganizationInviteDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "createOrganizationInvite").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		organizationID, err := uuid.Parse(params.ByName("organization_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("organization_id", params.ByName("organization_id")).Msg("invalid organization ID")
+			errorResponse(w, http.StatusBadRequest, "invalid organization ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			UserID uuid.UUID `json:"user_id"`
+			Role   string    `json:"role"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		invite := &db.OrganizationInvite{
+			OrganizationID: organizationID,
+			UserID:         input.UserID,
+			Role:           db.OrganizationRole(input.Role),
+			InvitedBy:      userID,
+		}
+
+		if invite.Role == "" {
+			invite.Role = db.OrganizationRoleMember
+		}
+
+		if errs := invite.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate invite")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.CreateOrganizationInvite(invite)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrOrganizationNotFound), errors.Is(err, db.ErrNotOrganizationMember):
+				logger.Error().Err(err).Msg("organization not found")
+				errorResponse(w, http.StatusNotFound, "organization not found")
+			case errors.Is(err, db.ErrNotOrganizationOwner):
+				logger.Error().Err(err).Msg("not an organization owner")
+				errorResponse(w, http.StatusForbidden, err.Error())
+			case errors.Is(err, db.ErrAlreadyOrganizationMember), errors.Is(err, db.ErrAlreadyInvited):
+				errorResponse(w, http.StatusConflict, err.Error())
+			default:
+				logger.Error().Err(err).Msg("failed to create invite")
+				errorResponse(w, http.StatusInternalServerError, "failed to create invite")
+			}
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusCreated, envelope{"invite": invite}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getOrganizationInvitesDatastore interface {
+	GetOrganizationRole(organizationID, userID uuid.UUID) (db.OrganizationRole, error)
+	GetOrganizationInvites(organizationID uuid.UUID) ([]db.OrganizationInvite, error)
+}
+
+// getOrganizationInvitesHandler lists an organization's pending invites for
+// its owners.
+func getOrganizationInvitesHandler(l *zerolog.Logger, datastore getOrganizationInvitesDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getOrganizationInvites").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		organizationID, err := uuid.Parse(params.ByName("organization_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("organization_id", params.ByName("organization_id")).Msg("invalid organization ID")
+			errorResponse(w, http.StatusBadRequest, "invalid organization ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		role, err := datastore.GetOrganizationRole(organizationID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrOrganizationNotFound), errors.Is(err, db.ErrNotOrganizationMember):
+				logger.Error().Err(err).Msg("organization not found")
+				errorResponse(w, http.StatusNotFound, "organization not found")
+			default:
+				logger.Error().Err(err).Msg("failed to get organization role")
+				errorResponse(w, http.StatusInternalServerError, "failed to get invites")
+			}
+
+			return
+		}
+
+		if role != db.OrganizationRoleOwner {
+			errorResponse(w, http.StatusForbidden, db.ErrNotOrganizationOwner.Error())
+			return
+		}
+
+		invites, err := datastore.GetOrganizationInvites(organizationID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get invites")
+			errorResponse(w, http.StatusInternalServerError, "failed to get invites")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"invites": invites}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getUserInvitesDatastore interface {
+	GetUserInvites(userID uuid.UUID) ([]db.OrganizationInvite, error)
+}
+
+// getUserInvitesHandler lists the invites waiting on the caller.
+func getUserInvitesHandler(l *zerolog.Logger, datastore getUserInvitesDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getUserInvites").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		invites, err := datastore.GetUserInvites(userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get invites")
+			errorResponse(w, http.StatusInternalServerError, "failed to get invites")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"invites": invites}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type acceptInviteDatastore interface {
+	AcceptOrganizationInvite(inviteID, userID uuid.UUID) (*db.OrganizationMember, error)
+}
+
+func acceptInviteHandler(l *zerolog.Logger, datastore acceptInviteDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "acceptInvite").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		inviteID, err := uuid.Parse(params.ByName("invite_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("invite_id", params.ByName("invite_id")).Msg("invalid invite ID")
+			errorResponse(w, http.StatusBadRequest, "invalid invite ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		member, err := datastore.AcceptOrganizationInvite(inviteID, userID)
+		if err != nil {
+			if errors.Is(err, db.ErrInviteNotFound) {
+				logger.Error().Err(err).Msg("invite not found")
+				errorResponse(w, http.StatusNotFound, "invite not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to accept invite")
+			errorResponse(w, http.StatusInternalServerError, "failed to accept invite")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"member": member}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type deleteInviteDatastore interface {
+	DeleteOrganizationInvite(inviteID, userID uuid.UUID) error
+}
+
+// deleteInviteHandler declines an invite, or revokes it when the caller owns
+// the organization.
+func deleteInviteHandler(l *zerolog.Logger, datastore deleteInviteDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "deleteInvite").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		inviteID, err := uuid.Parse(params.ByName("invite_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("invite_id", params.ByName("invite_id")).Msg("invalid invite ID")
+			errorResponse(w, http.StatusBadRequest, "invalid invite ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		err = datastore.DeleteOrganizationInvite(inviteID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrInviteNotFound):
+				logger.Error().Err(err).Msg("invite not found")
+				errorResponse(w, http.StatusNotFound, "invite not found")
+			case errors.Is(err, db.ErrNotOrganizationOwner):
+				logger.Error().Err(err).Msg("not an organization owner")
+				errorResponse(w, http.StatusForbidden, err.Error())
+			default:
+				logger.Error().Err(err).Msg("failed to delete invite")
+				errorResponse(w, http.StatusInternalServerError, "failed to delete invite")
+			}
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
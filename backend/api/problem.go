@@ -3,36 +3,59 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
 	"github.com/rs/zerolog"
 	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/validator"
 )
 
 type createProblemDatastore interface {
 	CreateProblem(boardID uuid.UUID, problem *db.Problem, holds []db.ProblemHold) error
 	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetHolds(boardID uuid.UUID) ([]db.Hold, error)
+	FindSimilarProblems(boardID, problemID uuid.UUID, holds []db.ProblemHold, minSimilarity float64, limit int) ([]db.SimilarProblem, error)
+	GetLatestGradeModel() (*db.GradeModel, error)
 }
 
 type getProblemsDatastore interface {
-	GetProblems(boardID uuid.UUID) ([]db.Problem, error)
+	GetProblems(boardID uuid.UUID, includeArchived, includeHidden bool) ([]db.Problem, error)
+	IsModerator(userID uuid.UUID) (bool, error)
+	GetCommentCounts(boardID uuid.UUID) (map[uuid.UUID]int, error)
 	GetBoard(id uuid.UUID) (*db.Board, error)
 }
 
 type getProblemDatastore interface {
 	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
 	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+	GetProblemLineage(problemID uuid.UUID) ([]db.Problem, error)
+	GetProblemForks(problemID uuid.UUID) ([]db.Problem, error)
 	GetBoard(id uuid.UUID) (*db.Board, error)
 }
 
 type updateProblemDatastore interface {
-	UpdateProblem(boardID uuid.UUID, problem *db.Problem, holds []db.ProblemHold) error
+	UpdateProblem(boardID uuid.UUID, problem *db.Problem, holds []db.ProblemHold, editorID uuid.UUID) error
 	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetHolds(boardID uuid.UUID) ([]db.Hold, error)
 }
 
-func createProblemHandler(l *zerolog.Logger, datastore createProblemDatastore) http.HandlerFunc {
+// maxDuplicateMatches caps how many near-duplicates are returned when warning
+// about a new or newly published problem.
+const maxDuplicateMatches = 5
+
+func duplicateWarning(duplicates []db.SimilarProblem) string {
+	if duplicates[0].Exact {
+		return "this problem uses exactly the same holds as an existing problem"
+	}
+
+	return "this problem is very similar to an existing problem"
+}
+
+func createProblemHandler(l *zerolog.Logger, datastore createProblemDatastore, publisher eventPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := l.With().Str("handler", "createProblem").Logger()
 
@@ -46,6 +69,14 @@ func createProblemHandler(l *zerolog.Logger, datastore createProblemDatastore) h
 			return
 		}
 
+		setterID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
 		// Check if board exists
 		_, err = datastore.GetBoard(boardID)
 		if err != nil {
@@ -63,9 +94,10 @@ func createProblemHandler(l *zerolog.Logger, datastore createProblemDatastore) h
 		}
 
 		var input struct {
-			Name   string `json:"name"`
-			Status string `json:"status"`
-			Holds  []struct {
+			Name  string          `json:"name"`
+			Grade *int            `json:"grade"`
+			Rules db.ProblemRules `json:"rules"`
+			Holds []struct {
 				ID   uuid.UUID `json:"id"`
 				Type string    `json:"type"`
 			} `json:"holds"`
@@ -79,41 +111,14 @@ func createProblemHandler(l *zerolog.Logger, datastore createProblemDatastore) h
 			return
 		}
 
-		// Validate input
-		if input.Name == "" {
-			errorResponse(w, http.StatusBadRequest, "name is required")
-			return
-		}
-
-		if input.Status != "DRAFT" && input.Status != "PUBLISHED" {
-			errorResponse(w, http.StatusBadRequest, "status must be either DRAFT or PUBLISHED")
-			return
-		}
-
-		if len(input.Holds) < 3 {
-			errorResponse(w, http.StatusBadRequest, "problem must have at least 3 holds")
-			return
-		}
-
-		startHolds := 0
-
-		for _, h := range input.Holds {
-			if h.Type == "start" {
-				startHolds++
-			}
-		}
-
-		if startHolds != 1 && startHolds != 2 {
-			errorResponse(w, http.StatusBadRequest, "problem must have exactly 1 or 2 start holds")
-			return
-		}
-
 		problem := &db.Problem{
 			ID:       uuid.New(),
 			BoardID:  boardID,
 			Name:     input.Name,
-			Status:   db.ProblemStatus(input.Status),
-			SetterID: uuid.MustParse("10000000-0000-0000-0000-000000000001"), // TODO: make users eventually
+			Status:   db.ProblemStatusDraft,
+			Grade:    input.Grade,
+			Rules:    input.Rules,
+			SetterID: setterID,
 		}
 
 		var problemHolds []db.ProblemHold
@@ -129,6 +134,21 @@ func createProblemHandler(l *zerolog.Logger, datastore createProblemDatastore) h
 			problemHolds = append(problemHolds, problemHold)
 		}
 
+		boardHolds, err := datastore.GetHolds(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get board holds")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		if errs := problem.Validate(problemHolds, boardHolds); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate problem")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
 		err = datastore.CreateProblem(boardID, problem, problemHolds)
 		if err != nil {
 			logger.Error().Err(err).Msg("failed to create problem")
@@ -137,7 +157,36 @@ func createProblemHandler(l *zerolog.Logger, datastore createProblemDatastore) h
 			return
 		}
 
-		err = writeJSON(w, http.StatusCreated, envelope{"problem": problem}, nil)
+		publisher.Publish(events.Event{Type: events.ProblemCreated, BoardID: boardID, Data: problem})
+
+		env := envelope{"problem": problem}
+
+		// Duplicates are only a warning, so the problem is created regardless
+		duplicates, err := datastore.FindSimilarProblems(boardID, problem.ID, problemHolds, db.NearDuplicateSimilarity, maxDuplicateMatches)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to check for duplicate problems")
+		} else if len(duplicates) > 0 {
+			env["warning"] = duplicateWarning(duplicates)
+			env["duplicates"] = duplicates
+		}
+
+		vertices := make(map[uuid.UUID][]db.Point, len(boardHolds))
+		for _, h := range boardHolds {
+			vertices[h.ID] = h.Vertices
+		}
+
+		for i := range problemHolds {
+			problemHolds[i].Vertices = vertices[problemHolds[i].HoldID]
+		}
+
+		suggestion, err := suggestGrade(datastore, problem.Rules, problemHolds)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to suggest grade")
+		} else if suggestion != nil {
+			env["suggested_grade"] = suggestion
+		}
+
+		err = writeJSON(w, http.StatusCreated, env, nil)
 		if err != nil {
 			logger.Error().Err(err).Msg("failed to write response")
 			return
@@ -159,8 +208,16 @@ func getProblemsHandler(l *zerolog.Logger, datastore getProblemsDatastore) http.
 			return
 		}
 
+		userID, err := readOptionalUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
 		// Check if board exists
-		_, err = datastore.GetBoard(boardID)
+		board, err := datastore.GetBoard(boardID)
 		if err != nil {
 			if errors.Is(err, db.ErrBoardNotFound) {
 				logger.Error().Err(err).Msg("board not found")
@@ -175,7 +232,18 @@ func getProblemsHandler(l *zerolog.Logger, datastore getProblemsDatastore) http.
 			return
 		}
 
-		problems, err := datastore.GetProblems(boardID)
+		moderator, err := canModerateBoard(datastore, board, userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to check moderator")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+		// Hidden problems are only shown to the people who can moderate them
+		problems, err := datastore.GetProblems(boardID, includeArchived, moderator)
 		if err != nil {
 			logger.Error().Err(err).Msg("failed to get problems")
 			errorResponse(w, http.StatusInternalServerError, "failed to get problems")
@@ -183,7 +251,25 @@ func getProblemsHandler(l *zerolog.Logger, datastore getProblemsDatastore) http.
 			return
 		}
 
-		err = writeJSON(w, http.StatusOK, envelope{"problems": problems}, nil)
+		counts, err := datastore.GetCommentCounts(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get comment counts")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problems")
+
+			return
+		}
+
+		type problemSummary struct {
+			db.Problem
+			CommentCount int `json:"comment_count"`
+		}
+
+		summaries := make([]problemSummary, len(problems))
+		for i, p := range problems {
+			summaries[i] = problemSummary{Problem: p, CommentCount: counts[p.ID]}
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"problems": summaries}, nil)
 		if err != nil {
 			logger.Error().Err(err).Msg("failed to write response")
 			return
@@ -252,12 +338,32 @@ func getProblemHandler(l *zerolog.Logger, datastore getProblemDatastore) http.Ha
 			return
 		}
 
+		lineage, err := datastore.GetProblemLineage(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem lineage")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem lineage")
+
+			return
+		}
+
+		forks, err := datastore.GetProblemForks(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem forks")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem forks")
+
+			return
+		}
+
 		response := struct {
 			*db.Problem
-			Holds []db.ProblemHold `json:"holds"`
+			Holds   []db.ProblemHold `json:"holds"`
+			Lineage []db.Problem     `json:"lineage"`
+			Forks   []db.Problem     `json:"forks"`
 		}{
 			Problem: problem,
 			Holds:   holds,
+			Lineage: lineage,
+			Forks:   forks,
 		}
 
 		err = writeJSON(w, http.StatusOK, envelope{"problem": response}, nil)
@@ -268,7 +374,7 @@ func getProblemHandler(l *zerolog.Logger, datastore getProblemDatastore) http.Ha
 	}
 }
 
-func updateProblemHandler(l *zerolog.Logger, datastore updateProblemDatastore) http.HandlerFunc {
+func updateProblemHandler(l *zerolog.Logger, datastore updateProblemDatastore, publisher eventPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := l.With().Str("handler", "updateProblem").Logger()
 
@@ -290,6 +396,14 @@ func updateProblemHandler(l *zerolog.Logger, datastore updateProblemDatastore) h
 			return
 		}
 
+		editorID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
 		// Check if board exists
 		_, err = datastore.GetBoard(boardID)
 		if err != nil {
@@ -307,9 +421,10 @@ func updateProblemHandler(l *zerolog.Logger, datastore updateProblemDatastore) h
 		}
 
 		var input struct {
-			Name   string `json:"name"`
-			Status string `json:"status"`
-			Holds  []struct {
+			Name  string          `json:"name"`
+			Grade *int            `json:"grade"`
+			Rules db.ProblemRules `json:"rules"`
+			Holds []struct {
 				ID   uuid.UUID `json:"id"`
 				Type string    `json:"type"`
 			} `json:"holds"`
@@ -323,60 +438,280 @@ func updateProblemHandler(l *zerolog.Logger, datastore updateProblemDatastore) h
 			return
 		}
 
-		if input.Name == "" {
-			errorResponse(w, http.StatusBadRequest, "name is required")
-			return
+		// Only drafts can be edited, which UpdateProblem enforces
+		problem := &db.Problem{
+			ID:      problemID,
+			BoardID: boardID,
+			Name:    input.Name,
+			Status:  db.ProblemStatusDraft,
+			Grade:   input.Grade,
+			Rules:   input.Rules,
+		}
+
+		var problemHolds []db.ProblemHold
+
+		for _, h := range input.Holds {
+			problemHold := db.ProblemHold{
+				HoldID: h.ID,
+				Type:   db.HoldType(h.Type),
+			}
+			problemHolds = append(problemHolds, problemHold)
 		}
 
-		if input.Status != "DRAFT" && input.Status != "PUBLISHED" {
-			errorResponse(w, http.StatusBadRequest, "status must be either DRAFT or PUBLISHED")
+		boardHolds, err := datastore.GetHolds(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get board holds")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
 			return
 		}
 
-		if len(input.Holds) < 3 {
-			errorResponse(w, http.StatusBadRequest, "problem must have at least 3 holds")
+		if errs := problem.Validate(problemHolds, boardHolds); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate problem")
+			failedValidationResponse(w, errs)
+
 			return
 		}
 
-		startHolds := 0
+		err = datastore.UpdateProblem(boardID, problem, problemHolds, editorID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotEditable) {
+				logger.Error().Err(err).Msg("problem is not a draft")
+				errorResponse(w, http.StatusConflict, "only draft problems can be edited")
 
-		for _, h := range input.Holds {
-			if h.Type == "start" {
-				startHolds++
+				return
+			}
+
+			if errors.Is(err, db.ErrNotProblemSetter) {
+				logger.Error().Err(err).Msg("editor is not the setter")
+				errorResponse(w, http.StatusForbidden, err.Error())
+
+				return
+			}
+
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
 			}
+
+			logger.Error().Err(err).Msg("failed to update problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to update problem")
+
+			return
 		}
 
-		if startHolds != 1 && startHolds != 2 {
-			errorResponse(w, http.StatusBadRequest, "problem must have exactly 1 or 2 start holds")
+		publisher.Publish(events.Event{Type: events.ProblemUpdated, BoardID: boardID, Data: problem})
+
+		err = writeJSON(w, http.StatusOK, envelope{"problem": problem}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
 			return
 		}
+	}
+}
 
-		problem := &db.Problem{
-			ID:      problemID,
-			BoardID: boardID,
-			Name:    input.Name,
-			Status:  db.ProblemStatus(input.Status),
+type forkProblemDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	IsProblemHidden(problemID uuid.UUID) (bool, error)
+	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+	GetHolds(boardID uuid.UUID) ([]db.Hold, error)
+	CreateProblem(boardID uuid.UUID, problem *db.Problem, holds []db.ProblemHold) error
+}
+
+// forkProblemHandler copies a published problem's holds and rules into a new
+// draft owned by the caller, which they can then edit as their own.
+func forkProblemHandler(l *zerolog.Logger, datastore forkProblemDatastore, publisher eventPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "forkProblem").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
 		}
 
-		var problemHolds []db.ProblemHold
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
 
-		for _, h := range input.Holds {
-			problemHold := db.ProblemHold{
-				HoldID: h.ID,
-				Type:   db.HoldType(h.Type),
+			return
+		}
+
+		setterID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Name string `json:"name"`
+		}
+
+		if r.ContentLength != 0 {
+			err = readJSON(w, r, &input)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to decode request body")
+				errorResponse(w, http.StatusBadRequest, err.Error())
+
+				return
 			}
-			problemHolds = append(problemHolds, problemHold)
 		}
 
-		err = datastore.UpdateProblem(boardID, problem, problemHolds)
+		parent, err := datastore.GetProblem(boardID, problemID)
 		if err != nil {
-			if err.Error() == "cannot edit published problem" {
-				logger.Error().Err(err).Msg("cannot edit published problem")
-				errorResponse(w, http.StatusBadRequest, "cannot edit published problem")
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
 
 				return
 			}
 
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		// Problems a moderator has hidden can't be copied back into view
+		hidden, err := datastore.IsProblemHidden(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to check if problem is hidden")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		if hidden {
+			errorResponse(w, http.StatusNotFound, "problem not found")
+			return
+		}
+
+		if parent.Status != db.ProblemStatusPublished && parent.Status != db.ProblemStatusArchived {
+			errorResponse(w, http.StatusConflict, "only published problems can be forked")
+			return
+		}
+
+		parentHolds, err := datastore.GetProblemHolds(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem holds")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem holds")
+
+			return
+		}
+
+		name := input.Name
+		if name == "" {
+			name = parent.Name + " (fork)"
+		}
+
+		problem := &db.Problem{
+			ID:       uuid.New(),
+			BoardID:  boardID,
+			Name:     name,
+			Status:   db.ProblemStatusDraft,
+			Grade:    parent.Grade,
+			Rules:    parent.Rules,
+			SetterID: setterID,
+			ParentID: &parent.ID,
+		}
+
+		problemHolds := make([]db.ProblemHold, 0, len(parentHolds))
+		for _, h := range parentHolds {
+			problemHolds = append(problemHolds, db.ProblemHold{
+				ID:        uuid.New(),
+				ProblemID: problem.ID,
+				HoldID:    h.HoldID,
+				Type:      h.Type,
+			})
+		}
+
+		boardHolds, err := datastore.GetHolds(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get board holds")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		// The name may be too long once "(fork)" is added, and holds may have
+		// been removed from the board since the parent was set
+		if errs := problem.Validate(problemHolds, boardHolds); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate fork")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.CreateProblem(boardID, problem, problemHolds)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to fork problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to fork problem")
+
+			return
+		}
+
+		publisher.Publish(events.Event{Type: events.ProblemCreated, BoardID: boardID, Data: problem})
+
+		headers := make(http.Header)
+		headers.Set("Location", fmt.Sprintf("/v1/board/%s/problem/%s", boardID, problem.ID))
+
+		err = writeJSON(w, http.StatusCreated, envelope{"problem": problem}, headers)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getSimilarProblemsDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+	FindSimilarProblems(boardID, problemID uuid.UUID, holds []db.ProblemHold, minSimilarity float64, limit int) ([]db.SimilarProblem, error)
+}
+
+func getSimilarProblemsHandler(l *zerolog.Logger, datastore getSimilarProblemsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getSimilarProblems").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		v := validator.New()
+
+		limit := readInt(r.URL.Query(), "limit", 10, v)
+		v.Check(limit > 0 && limit <= 100, "limit", "must be between 1 and 100")
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
 			if errors.Is(err, db.ErrProblemNotFound) {
 				logger.Error().Err(err).Msg("problem not found")
 				errorResponse(w, http.StatusNotFound, "problem not found")
@@ -384,13 +719,29 @@ func updateProblemHandler(l *zerolog.Logger, datastore updateProblemDatastore) h
 				return
 			}
 
-			logger.Error().Err(err).Msg("failed to update problem")
-			errorResponse(w, http.StatusInternalServerError, "failed to update problem")
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
 
 			return
 		}
 
-		err = writeJSON(w, http.StatusOK, envelope{"problem": problem}, nil)
+		holds, err := datastore.GetProblemHolds(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem holds")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem holds")
+
+			return
+		}
+
+		similar, err := datastore.FindSimilarProblems(boardID, problemID, holds, 0, limit)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to find similar problems")
+			errorResponse(w, http.StatusInternalServerError, "failed to find similar problems")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"similar": similar}, nil)
 		if err != nil {
 			logger.Error().Err(err).Msg("failed to write response")
 			return
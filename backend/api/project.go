@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+type projectDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	AddProject(userID, problemID uuid.UUID) error
+	RemoveProject(userID, problemID uuid.UUID) error
+}
+
+func addProjectHandler(l *zerolog.Logger, datastore projectDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "addProject").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		problem, err := datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		if problem.Status != db.ProblemStatusPublished {
+			errorResponse(w, http.StatusConflict, "only published problems can be projects")
+			return
+		}
+
+		err = datastore.AddProject(userID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrAlreadySent) {
+				errorResponse(w, http.StatusConflict, err.Error())
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to add project")
+			errorResponse(w, http.StatusInternalServerError, "failed to add project")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func removeProjectHandler(l *zerolog.Logger, datastore projectDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "removeProject").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		err = datastore.RemoveProject(userID, problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to remove project")
+			errorResponse(w, http.StatusInternalServerError, "failed to remove project")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type getProjectsDatastore interface {
+	GetProjects(userID uuid.UUID) ([]db.Project, error)
+}
+
+// getProjectsHandler lists the problems the caller is working on. Problems
+// drop off the list as soon as a send is logged on them.
+func getProjectsHandler(l *zerolog.Logger, datastore getProjectsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getProjects").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		projects, err := datastore.GetProjects(userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get projects")
+			errorResponse(w, http.StatusInternalServerError, "failed to get projects")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"projects": projects}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
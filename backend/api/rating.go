@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+type rateProblemDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	RateProblem(r *db.Rating) error
+}
+
+func rateProblemHandler(l *zerolog.Logger, datastore rateProblemDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "rateProblem").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Stars int `json:"stars"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		rating := &db.Rating{
+			ProblemID: problemID,
+			UserID:    userID,
+			Stars:     input.Stars,
+		}
+
+		if errs := rating.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate rating")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		problem, err := datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		if problem.Status != db.ProblemStatusPublished {
+			errorResponse(w, http.StatusConflict, "only published problems can be rated")
+			return
+		}
+
+		err = datastore.RateProblem(rating)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to rate problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to rate problem")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"rating": rating}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
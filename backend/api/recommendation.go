@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type getRecommendationsDatastore interface {
+	GetRecommendationInput(userID uuid.UUID, boardID *uuid.UUID) (*db.RecommendationInput, error)
+}
+
+// getRecommendationsHandler suggests published problems the user hasn't sent
+// yet, best first. It can be limited to one board with ?board_id=.
+func getRecommendationsHandler(l *zerolog.Logger, datastore getRecommendationsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getRecommendations").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		qs := r.URL.Query()
+
+		var boardID *uuid.UUID
+
+		if idStr := qs.Get("board_id"); idStr != "" {
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				logger.Error().Err(err).Str("board_id", idStr).Msg("invalid board ID")
+				errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+				return
+			}
+
+			boardID = &id
+		}
+
+		v := validator.New()
+
+		limit := readInt(qs, "limit", 20, v)
+		v.Check(limit >= 1 && limit <= 100, "limit", "must be between 1 and 100")
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		input, err := datastore.GetRecommendationInput(userID, boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get recommendation input")
+			errorResponse(w, http.StatusInternalServerError, "failed to get recommendations")
+
+			return
+		}
+
+		recommendations := db.RankRecommendations(*input, limit)
+
+		err = writeJSON(w, http.StatusOK, envelope{"recommendations": recommendations}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
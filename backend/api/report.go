@@ -0,0 +1,237 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type moderatorChecker interface {
+	IsModerator(userID uuid.UUID) (bool, error)
+}
+
+// canModerateBoard reports whether a user can moderate the content on a
+// board, which its owner and site moderators can. Anonymous callers never
+// can, and neither can the placeholder user that owns boards made before
+// there were users.
+func canModerateBoard(checker moderatorChecker, board *db.Board, userID uuid.UUID) (bool, error) {
+	if userID == uuid.Nil || userID == defaultUserID {
+		return false, nil
+	}
+
+	if board.OwnerID == userID {
+		return true, nil
+	}
+
+	return checker.IsModerator(userID) //nolint:wrapcheck
+}
+
+type createReportDatastore interface {
+	CreateReport(r *db.Report) error
+}
+
+func createReportHandler(l *zerolog.Logger, datastore createReportDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "createReport").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			TargetType string    `json:"target_type"`
+			TargetID   uuid.UUID `json:"target_id"`
+			Reason     string    `json:"reason"`
+			Details    string    `json:"details"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		report := &db.Report{
+			TargetType: db.ReportTarget(input.TargetType),
+			TargetID:   input.TargetID,
+			ReporterID: userID,
+			Reason:     db.ReportReason(input.Reason),
+			Details:    input.Details,
+		}
+
+		if errs := report.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate report")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.CreateReport(report)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrReportTargetNotFound):
+				errorResponse(w, http.StatusNotFound, err.Error())
+			case errors.Is(err, db.ErrAlreadyReported):
+				errorResponse(w, http.StatusConflict, "you have already reported this")
+			default:
+				logger.Error().Err(err).Msg("failed to create report")
+				errorResponse(w, http.StatusInternalServerError, "failed to create report")
+			}
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusCreated, envelope{"report": report}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getReportsDatastore interface {
+	GetReports(userID uuid.UUID, status db.ReportStatus, p db.Pagination) ([]db.Report, db.Metadata, error)
+}
+
+// getReportsHandler is the moderation queue: the reports the caller can act
+// on, oldest first. ?status= is one of open, actioned or dismissed and
+// defaults to open.
+func getReportsHandler(l *zerolog.Logger, datastore getReportsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getReports").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		qs := r.URL.Query()
+		v := validator.New()
+
+		status := db.ReportStatus(qs.Get("status"))
+		if status == "" {
+			status = db.ReportStatusOpen
+		}
+
+		v.Check(
+			validator.PermittedValue(status, db.ReportStatusOpen, db.ReportStatusActioned, db.ReportStatusDismissed),
+			"status", "must be one of open, actioned or dismissed",
+		)
+
+		pagination := db.Pagination{
+			Page:     readInt(qs, "page", 1, v),
+			PageSize: readInt(qs, "page_size", 20, v),
+		}
+
+		for key, message := range pagination.Validate() {
+			v.AddError(key, message)
+		}
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		reports, metadata, err := datastore.GetReports(userID, status, pagination)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get reports")
+			errorResponse(w, http.StatusInternalServerError, "failed to get reports")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"reports": reports, "metadata": metadata}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type resolveReportDatastore interface {
+	ResolveReport(reportID, userID uuid.UUID, action db.ModerationAction) (*db.Report, error)
+}
+
+// resolveReportHandler hides or deletes reported content, or dismisses the
+// report. Every open report on the same content is resolved with it.
+func resolveReportHandler(l *zerolog.Logger, datastore resolveReportDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "resolveReport").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		reportID, err := uuid.Parse(params.ByName("report_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("report_id", params.ByName("report_id")).Msg("invalid report ID")
+			errorResponse(w, http.StatusBadRequest, "invalid report ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Action string `json:"action"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		action := db.ModerationAction(input.Action)
+
+		if !validator.PermittedValue(action, db.ModerationActionHide, db.ModerationActionDelete, db.ModerationActionDismiss) {
+			failedValidationResponse(w, map[string]string{"action": "must be one of hide, delete or dismiss"})
+			return
+		}
+
+		report, err := datastore.ResolveReport(reportID, userID, action)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrReportNotFound):
+				logger.Error().Err(err).Msg("report not found")
+				errorResponse(w, http.StatusNotFound, "report not found")
+			case errors.Is(err, db.ErrNotModerator):
+				logger.Error().Err(err).Msg("not a moderator")
+				errorResponse(w, http.StatusForbidden, err.Error())
+			case errors.Is(err, db.ErrReportResolved):
+				errorResponse(w, http.StatusConflict, err.Error())
+			default:
+				logger.Error().Err(err).Msg("failed to resolve report")
+				errorResponse(w, http.StatusInternalServerError, "failed to resolve report")
+			}
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"report": report}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
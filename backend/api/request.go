@@ -6,9 +6,56 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+// defaultUserID is the placeholder user that set every problem and owned every
+// board made before there were users.
+var defaultUserID = uuid.MustParse("10000000-0000-0000-0000-000000000001")
+
+var (
+	errMissingUserID  = errors.New("missing X-User-ID header")
+	errReservedUserID = errors.New("invalid X-User-ID header: reserved user ID")
 )
 
+// readUserID returns the ID of the user making the request, taken from the
+// X-User-ID header. It returns errMissingUserID if there isn't one.
+func readUserID(r *http.Request) (uuid.UUID, error) {
+	idStr := r.Header.Get("X-User-ID")
+	if idStr == "" {
+		return uuid.Nil, errMissingUserID
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid X-User-ID header: %v", err)
+	}
+
+	// Nobody can claim to be the placeholder user, who owns everything made
+	// before there were users, or the anonymous caller
+	if id == defaultUserID || id == uuid.Nil {
+		return uuid.Nil, errReservedUserID
+	}
+
+	return id, nil
+}
+
+// readOptionalUserID is readUserID for routes anyone can read. Callers without
+// an X-User-ID header get uuid.Nil, which owns nothing and belongs to nothing.
+func readOptionalUserID(r *http.Request) (uuid.UUID, error) {
+	id, err := readUserID(r)
+	if errors.Is(err, errMissingUserID) {
+		return uuid.Nil, nil
+	}
+
+	return id, err
+}
+
 func readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	maxBytes := 25 << 20 // 25MB
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -65,3 +112,20 @@ func readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 
 	return nil
 }
+
+// readInt reads an integer from the query string, returning defaultValue when
+// the key is absent and recording an error in v when it isn't an integer.
+func readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return i
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -36,6 +37,31 @@ func errorResponse(w http.ResponseWriter, status int, message any) {
 	}
 }
 
+// userIDErrorResponse reports a missing or invalid X-User-ID header.
+func userIDErrorResponse(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMissingUserID) {
+		errorResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	errorResponse(w, http.StatusBadRequest, err.Error())
+}
+
 func notFoundResponse(w http.ResponseWriter) {
 	errorResponse(w, http.StatusNotFound, "the requested resource could not be found")
 }
+
+// failedValidationResponse reports every field error from a validator under
+// "fields", keyed by the path of the field in the request body, for example
+// "holds[2].vertices[0].x".
+func failedValidationResponse(w http.ResponseWriter, errs map[string]string) {
+	env := envelope{
+		"error":  "the request contains invalid fields",
+		"fields": errs,
+	}
+
+	err := writeJSON(w, http.StatusUnprocessableEntity, env, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
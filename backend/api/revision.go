@@ -0,0 +1,280 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type getProblemRevisionsDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetProblemRevisions(problemID uuid.UUID) ([]db.ProblemRevision, error)
+}
+
+type diffProblemRevisionsDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetProblemRevision(problemID uuid.UUID, number int) (*db.ProblemRevision, error)
+}
+
+type revertProblemDatastore interface {
+	GetProblemRevision(problemID uuid.UUID, number int) (*db.ProblemRevision, error)
+	GetHolds(boardID uuid.UUID) ([]db.Hold, error)
+	UpdateProblem(boardID uuid.UUID, problem *db.Problem, holds []db.ProblemHold, editorID uuid.UUID) error
+}
+
+func getProblemRevisionsHandler(l *zerolog.Logger, datastore getProblemRevisionsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getProblemRevisions").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		revisions, err := datastore.GetProblemRevisions(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem revisions")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem revisions")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"revisions": revisions}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+func diffProblemRevisionsHandler(l *zerolog.Logger, datastore diffProblemRevisionsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "diffProblemRevisions").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		from := readInt(qs, "from", 0, v)
+		to := readInt(qs, "to", 0, v)
+
+		v.Check(from > 0, "from", "must be a revision number")
+		v.Check(to > 0, "to", "must be a revision number")
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		revisions := make([]*db.ProblemRevision, 0, 2)
+
+		for _, number := range []int{from, to} {
+			revision, err := datastore.GetProblemRevision(problemID, number)
+			if err != nil {
+				if errors.Is(err, db.ErrRevisionNotFound) {
+					logger.Error().Err(err).Int("revision", number).Msg("revision not found")
+					errorResponse(w, http.StatusNotFound, "revision not found")
+
+					return
+				}
+
+				logger.Error().Err(err).Msg("failed to get problem revision")
+				errorResponse(w, http.StatusInternalServerError, "failed to get problem revision")
+
+				return
+			}
+
+			revisions = append(revisions, revision)
+		}
+
+		diff := db.DiffRevisions(*revisions[0], *revisions[1])
+
+		err = writeJSON(w, http.StatusOK, envelope{"diff": diff}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+// revertProblemHandler restores a draft to an earlier revision. The restored
+// problem is saved as a new revision, so history is never rewritten.
+func revertProblemHandler(l *zerolog.Logger, datastore revertProblemDatastore, publisher eventPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "revertProblem").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		number, err := strconv.Atoi(params.ByName("revision"))
+		if err != nil {
+			logger.Error().Err(err).Str("revision", params.ByName("revision")).Msg("invalid revision number")
+			errorResponse(w, http.StatusBadRequest, "invalid revision number")
+
+			return
+		}
+
+		editorID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		revision, err := datastore.GetProblemRevision(problemID, number)
+		if err != nil {
+			if errors.Is(err, db.ErrRevisionNotFound) {
+				logger.Error().Err(err).Msg("revision not found")
+				errorResponse(w, http.StatusNotFound, "revision not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem revision")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem revision")
+
+			return
+		}
+
+		problem := &db.Problem{
+			ID:      problemID,
+			BoardID: boardID,
+			Name:    revision.Name,
+			Status:  db.ProblemStatusDraft,
+			Grade:   revision.Grade,
+			Rules:   revision.Rules,
+		}
+
+		problemHolds := make([]db.ProblemHold, 0, len(revision.Holds))
+		for _, h := range revision.Holds {
+			problemHolds = append(problemHolds, db.ProblemHold{HoldID: h.HoldID, Type: h.Type})
+		}
+
+		boardHolds, err := datastore.GetHolds(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get board holds")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		// Holds may have been removed from the board since the revision was made
+		if errs := problem.Validate(problemHolds, boardHolds); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("revision is no longer valid")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.UpdateProblem(boardID, problem, problemHolds, editorID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrProblemNotEditable):
+				logger.Error().Err(err).Msg("problem is not a draft")
+				errorResponse(w, http.StatusConflict, "only draft problems can be reverted")
+			case errors.Is(err, db.ErrNotProblemSetter):
+				logger.Error().Err(err).Msg("editor is not the setter")
+				errorResponse(w, http.StatusForbidden, err.Error())
+			case errors.Is(err, db.ErrProblemNotFound):
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+			default:
+				logger.Error().Err(err).Msg("failed to revert problem")
+				errorResponse(w, http.StatusInternalServerError, "failed to revert problem")
+			}
+
+			return
+		}
+
+		publisher.Publish(events.Event{Type: events.ProblemUpdated, BoardID: boardID, Data: problem})
+
+		err = writeJSON(w, http.StatusOK, envelope{"problem": problem}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
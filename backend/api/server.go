@@ -10,12 +10,16 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/rs/zerolog"
 	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/webhooks"
 )
 
 type Server struct {
 	httpServer      *http.Server
 	shutdownTimeout time.Duration
 	logger          *zerolog.Logger
+	broker          *events.Broker
+	dispatcher      *webhooks.Dispatcher
 }
 
 type ServerOption func(*Server)
@@ -68,6 +72,8 @@ func NewServer(l *zerolog.Logger, db *db.DB, opts ...ServerOption) *Server {
 			MaxHeaderBytes:    1 << 20, // 1MB for headers
 		},
 		shutdownTimeout: 5 * time.Second,
+		broker:          events.NewBroker(),
+		dispatcher:      webhooks.NewDispatcher(l, db),
 	}
 
 	for _, opt := range opts {
@@ -82,7 +88,7 @@ func NewServer(l *zerolog.Logger, db *db.DB, opts ...ServerOption) *Server {
 			// Set CORS headers for preflight requests
 			header := w.Header()
 			header.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			header.Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-Requested-With")
+			header.Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-Requested-With, X-User-ID")
 			header.Set("Access-Control-Allow-Origin", "*")
 			header.Set("Access-Control-Max-Age", "3600")
 			header.Set("Access-Control-Allow-Credentials", "true")
@@ -91,18 +97,94 @@ func NewServer(l *zerolog.Logger, db *db.DB, opts ...ServerOption) *Server {
 		w.WriteHeader(http.StatusNoContent)
 	})
 
+	// Board-scoped routes only serve users who can see the board
+	boardAccess := func(next http.HandlerFunc) http.HandlerFunc { return requireBoardAccess(l, db, next) }
+
 	router.HandlerFunc(http.MethodPost, "/v1/board", createBoardHandler(l, db))
 	router.HandlerFunc(http.MethodGet, "/v1/boards", getAllBoardsHandler(l, db))
-	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id", getBoardHandler(l, db))
-	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/holds", createHoldsOnBoardHandler(l, db))
-	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/holds", getHoldsOnBoardHandler(l, db))
-	router.HandlerFunc(http.MethodPatch, "/v1/board/:board_id/holds", updateHoldsOnBoardHandler(l, db))
-	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem", createProblemHandler(l, db))
-	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problems", getProblemsHandler(l, db))
-	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id", getProblemHandler(l, db))
-	router.HandlerFunc(http.MethodPatch, "/v1/board/:board_id/problem/:problem_id", updateProblemHandler(l, db))
-	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/attempt", createAttemptHandler(l, db))
-	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/attempt", getAttemptHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id", boardAccess(getBoardHandler(l, db)))
+	router.HandlerFunc(http.MethodPatch, "/v1/board/:board_id", boardAccess(updateBoardHandler(l, db)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/holds", boardAccess(createHoldsOnBoardHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/holds", boardAccess(getHoldsOnBoardHandler(l, db)))
+	router.HandlerFunc(http.MethodPatch, "/v1/board/:board_id/holds", boardAccess(updateHoldsOnBoardHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/mirror", boardAccess(getBoardMirrorHandler(l, db)))
+	router.HandlerFunc(http.MethodPut, "/v1/board/:board_id/mirror", boardAccess(setBoardMirrorHandler(l, db)))
+	router.HandlerFunc(http.MethodDelete, "/v1/board/:board_id/mirror", boardAccess(clearBoardMirrorHandler(l, db)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/generate", boardAccess(generateProblemHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/leaderboard", boardAccess(getLeaderboardHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/events", boardAccess(getBoardEventsHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/webhooks", boardAccess(createWebhookHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/webhooks", boardAccess(getWebhooksHandler(l, db)))
+	router.HandlerFunc(http.MethodDelete, "/v1/board/:board_id/webhooks/:webhook_id", boardAccess(deleteWebhookHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/webhooks/:webhook_id/deliveries", boardAccess(getWebhookDeliveriesHandler(l, db)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem", boardAccess(createProblemHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problems", boardAccess(getProblemsHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id", boardAccess(getProblemHandler(l, db)))
+	router.HandlerFunc(http.MethodPatch, "/v1/board/:board_id/problem/:problem_id", boardAccess(updateProblemHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/predicted-grade", boardAccess(getGradePredictionHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/similar", boardAccess(getSimilarProblemsHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/mirror", boardAccess(getMirroredProblemHandler(l, db)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/fork", boardAccess(forkProblemHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/submit", boardAccess(submitProblemHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/approve", boardAccess(approveProblemHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/reject", boardAccess(rejectProblemHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/archive", boardAccess(archiveProblemHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/transitions", boardAccess(getProblemTransitionsHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/revisions", boardAccess(getProblemRevisionsHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/revisions/diff", boardAccess(diffProblemRevisionsHandler(l, db)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/revisions/:revision/revert", boardAccess(revertProblemHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/beta", boardAccess(createBetaHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/beta", boardAccess(getBetasHandler(l, db)))
+	router.HandlerFunc(http.MethodDelete, "/v1/board/:board_id/problem/:problem_id/beta/:beta_id", boardAccess(deleteBetaHandler(l, db)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/comment", boardAccess(createCommentHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/comment", boardAccess(getCommentsHandler(l, db)))
+	router.HandlerFunc(http.MethodPatch, "/v1/board/:board_id/problem/:problem_id/comment/:comment_id", boardAccess(updateCommentHandler(l, db)))
+	router.HandlerFunc(http.MethodDelete, "/v1/board/:board_id/problem/:problem_id/comment/:comment_id", boardAccess(deleteCommentHandler(l, db)))
+	router.HandlerFunc(http.MethodPost, "/v1/board/:board_id/problem/:problem_id/attempt", boardAccess(createAttemptHandler(l, db, s.broker)))
+	router.HandlerFunc(http.MethodGet, "/v1/board/:board_id/problem/:problem_id/attempt", boardAccess(getAttemptHandler(l, db)))
+	router.HandlerFunc(http.MethodPut, "/v1/board/:board_id/problem/:problem_id/rating", boardAccess(rateProblemHandler(l, db)))
+	router.HandlerFunc(http.MethodPut, "/v1/board/:board_id/problem/:problem_id/favourite", boardAccess(addFavouriteHandler(l, db)))
+	router.HandlerFunc(http.MethodDelete, "/v1/board/:board_id/problem/:problem_id/favourite", boardAccess(removeFavouriteHandler(l, db)))
+	router.HandlerFunc(http.MethodPut, "/v1/board/:board_id/problem/:problem_id/project", boardAccess(addProjectHandler(l, db)))
+	router.HandlerFunc(http.MethodDelete, "/v1/board/:board_id/problem/:problem_id/project", boardAccess(removeProjectHandler(l, db)))
+	router.HandlerFunc(http.MethodGet, "/v1/me/recommendations", getRecommendationsHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/me/favourites", getFavouritesHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/me/projects", getProjectsHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/me/feed", getFeedHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/me/invites", getUserInvitesHandler(l, db))
+	router.HandlerFunc(http.MethodPost, "/v1/organization", createOrganizationHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/organizations", getOrganizationsHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/organization/:organization_id", getOrganizationHandler(l, db))
+	router.HandlerFunc(http.MethodDelete, "/v1/organization/:organization_id/members/:user_id", removeOrganizationMemberHandler(l, db))
+	router.HandlerFunc(http.MethodPost, "/v1/organization/:organization_id/invites", createOrganizationInviteHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/organization/:organization_id/invites", getOrganizationInvitesHandler(l, db))
+	router.HandlerFunc(http.MethodPost, "/v1/invite/:invite_id/accept", acceptInviteHandler(l, db))
+	router.HandlerFunc(http.MethodDelete, "/v1/invite/:invite_id", deleteInviteHandler(l, db))
+	router.HandlerFunc(http.MethodPost, "/v1/report", createReportHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/reports", getReportsHandler(l, db))
+	router.HandlerFunc(http.MethodPost, "/v1/report/:report_id/resolve", resolveReportHandler(l, db))
+	router.HandlerFunc(http.MethodPost, "/v1/circuit", createCircuitHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/circuits", getCircuitsHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/circuit/:circuit_id", getCircuitHandler(l, db))
+	router.HandlerFunc(http.MethodPut, "/v1/circuit/:circuit_id", updateCircuitHandler(l, db))
+	router.HandlerFunc(http.MethodDelete, "/v1/circuit/:circuit_id", deleteCircuitHandler(l, db))
+	router.HandlerFunc(http.MethodPost, "/v1/session", startSessionHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/sessions", getSessionsHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/session/:session_id", getSessionHandler(l, db))
+	router.HandlerFunc(http.MethodPost, "/v1/session/:session_id/end", endSessionHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/users/:user_id/stats", getUserStatsHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/users/:user_id/achievements", getUserAchievementsHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/achievements", getAchievementsHandler(l))
+	router.HandlerFunc(http.MethodPut, "/v1/users/:user_id/follow", followUserHandler(l, db))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/:user_id/follow", unfollowUserHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/users/:user_id/following", getFollowsHandler(l, db, false))
+	router.HandlerFunc(http.MethodGet, "/v1/users/:user_id/followers", getFollowsHandler(l, db, true))
+	router.HandlerFunc(http.MethodPost, "/v1/competition", createCompetitionHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/competitions", getCompetitionsHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/competition/:competition_id", getCompetitionHandler(l, db))
+	router.HandlerFunc(http.MethodPost, "/v1/competition/:competition_id/register", registerCompetitorHandler(l, db))
+	router.HandlerFunc(http.MethodDelete, "/v1/competition/:competition_id/register", withdrawCompetitorHandler(l, db))
+	router.HandlerFunc(http.MethodGet, "/v1/competition/:competition_id/results", getCompetitionResultsHandler(l, db))
 
 	// Wrap the router with CORS middleware and max body size middleware
 	handler := enableCORS(maxBodySize(router, 25<<20)) // 25MB limit
@@ -115,6 +197,8 @@ func (s *Server) Start() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
 
+	s.dispatcher.Start()
+
 	go func() {
 		s.logger.Info().Str("address", s.httpServer.Addr).Msg("server listening")
 
@@ -128,6 +212,10 @@ func (s *Server) Start() {
 
 	s.logger.Info().Msg("shutdown signal received")
 
+	// Event streams never finish on their own, so end them before waiting for
+	// requests to drain
+	s.broker.Close()
+
 	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 	defer cancel()
 
@@ -135,4 +223,7 @@ func (s *Server) Start() {
 	if err != nil {
 		s.logger.Error().Err(err).Msg("server shutdown error")
 	}
+
+	// Anything queued but not yet sent stays in the outbox for next time
+	s.dispatcher.Stop()
 }
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+type startSessionDatastore interface {
+	StartSession(userID uuid.UUID) (*db.Session, error)
+}
+
+func startSessionHandler(l *zerolog.Logger, datastore startSessionDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "startSession").Logger()
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		session, err := datastore.StartSession(userID)
+		if err != nil {
+			if errors.Is(err, db.ErrSessionOpen) {
+				errorResponse(w, http.StatusConflict, err.Error())
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to start session")
+			errorResponse(w, http.StatusInternalServerError, "failed to start session")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusCreated, envelope{"session": session}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type endSessionDatastore interface {
+	EndSession(sessionID, userID uuid.UUID) (*db.Session, error)
+	GetSessionSummary(sessionID, viewerID uuid.UUID) (*db.SessionSummary, error)
+}
+
+func endSessionHandler(l *zerolog.Logger, datastore endSessionDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "endSession").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		sessionID, err := uuid.Parse(params.ByName("session_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("session_id", params.ByName("session_id")).Msg("invalid session ID")
+			errorResponse(w, http.StatusBadRequest, "invalid session ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		_, err = datastore.EndSession(sessionID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrSessionNotFound):
+				logger.Error().Err(err).Msg("session not found")
+				errorResponse(w, http.StatusNotFound, "session not found")
+
+				return
+			case errors.Is(err, db.ErrNotSessionOwner):
+				logger.Error().Err(err).Msg("not session owner")
+				errorResponse(w, http.StatusForbidden, "only the climber can end their session")
+
+				return
+			case errors.Is(err, db.ErrSessionEnded):
+				errorResponse(w, http.StatusConflict, err.Error())
+				return
+			default:
+				logger.Error().Err(err).Msg("failed to end session")
+				errorResponse(w, http.StatusInternalServerError, "failed to end session")
+
+				return
+			}
+		}
+
+		summary, err := datastore.GetSessionSummary(sessionID, userID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get session summary")
+			errorResponse(w, http.StatusInternalServerError, "failed to get session summary")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"session": summary}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getSessionDatastore interface {
+	GetSessionSummary(sessionID, viewerID uuid.UUID) (*db.SessionSummary, error)
+}
+
+func getSessionHandler(l *zerolog.Logger, datastore getSessionDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getSession").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		sessionID, err := uuid.Parse(params.ByName("session_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("session_id", params.ByName("session_id")).Msg("invalid session ID")
+			errorResponse(w, http.StatusBadRequest, "invalid session ID")
+
+			return
+		}
+
+		viewerID, err := readOptionalUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		summary, err := datastore.GetSessionSummary(sessionID, viewerID)
+		if err != nil {
+			if errors.Is(err, db.ErrSessionNotFound) {
+				logger.Error().Err(err).Msg("session not found")
+				errorResponse(w, http.StatusNotFound, "session not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get session summary")
+			errorResponse(w, http.StatusInternalServerError, "failed to get session summary")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"session": summary}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getSessionsDatastore interface {
+	GetSessionSummaries(userID, viewerID uuid.UUID) ([]db.SessionSummary, error)
+}
+
+// getSessionsHandler lists a climber's sessions, by default the caller's,
+// including the ones their attempts were grouped into automatically.
+func getSessionsHandler(l *zerolog.Logger, datastore getSessionsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getSessions").Logger()
+
+		viewerID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		userID := viewerID
+
+		if idStr := r.URL.Query().Get("user_id"); idStr != "" {
+			userID, err = uuid.Parse(idStr)
+			if err != nil {
+				logger.Error().Err(err).Str("user_id", idStr).Msg("invalid user ID")
+				errorResponse(w, http.StatusBadRequest, "invalid user ID")
+
+				return
+			}
+		}
+
+		sessions, err := datastore.GetSessionSummaries(userID, viewerID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get sessions")
+			errorResponse(w, http.StatusInternalServerError, "failed to get sessions")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"sessions": sessions}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
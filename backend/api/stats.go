@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+type getUserStatsDatastore interface {
+	GetStatsAttempts(userID, viewerID uuid.UUID) ([]db.StatsAttempt, error)
+}
+
+func getUserStatsHandler(l *zerolog.Logger, datastore getUserStatsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getUserStats").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		userID, err := uuid.Parse(params.ByName("user_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("user_id", params.ByName("user_id")).Msg("invalid user ID")
+			errorResponse(w, http.StatusBadRequest, "invalid user ID")
+
+			return
+		}
+
+		viewerID, err := readOptionalUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		attempts, err := datastore.GetStatsAttempts(userID, viewerID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get attempts")
+			errorResponse(w, http.StatusInternalServerError, "failed to get stats")
+
+			return
+		}
+
+		stats := db.ComputeUserStats(userID, attempts, time.Now())
+
+		err = writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
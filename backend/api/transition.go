@@ -0,0 +1,216 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
+)
+
+type transitionProblemDatastore interface {
+	TransitionProblem(boardID uuid.UUID, t *db.ProblemTransition) error
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetProblemHolds(problemID uuid.UUID) ([]db.ProblemHold, error)
+	FindSimilarProblems(boardID, problemID uuid.UUID, holds []db.ProblemHold, minSimilarity float64, limit int) ([]db.SimilarProblem, error)
+}
+
+type getProblemTransitionsDatastore interface {
+	GetProblem(boardID, problemID uuid.UUID) (*db.Problem, error)
+	GetProblemTransitions(problemID uuid.UUID) ([]db.ProblemTransition, error)
+}
+
+// transitionProblemHandler moves a problem through one step of its lifecycle.
+// The request body is optional and may carry a comment, which is required
+// when rejecting a problem so the setter knows what to change.
+func transitionProblemHandler(l *zerolog.Logger, datastore transitionProblemDatastore, publisher eventPublisher, action db.ProblemAction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "transitionProblem").Str("action", string(action)).Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		var input struct {
+			Comment string `json:"comment"`
+		}
+
+		if r.ContentLength != 0 {
+			err = readJSON(w, r, &input)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to decode request body")
+				errorResponse(w, http.StatusBadRequest, err.Error())
+
+				return
+			}
+		}
+
+		if action == db.ProblemActionReject && input.Comment == "" {
+			failedValidationResponse(w, map[string]string{"comment": "must be provided when rejecting a problem"})
+			return
+		}
+
+		transition := &db.ProblemTransition{
+			ProblemID: problemID,
+			Action:    action,
+			ActorID:   userID,
+			Comment:   input.Comment,
+		}
+
+		err = datastore.TransitionProblem(boardID, transition)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrProblemNotFound):
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+			case errors.Is(err, db.ErrInvalidTransition):
+				logger.Error().Err(err).Msg("invalid transition")
+				errorResponse(w, http.StatusConflict, err.Error())
+			case errors.Is(err, db.ErrNotProblemSetter), errors.Is(err, db.ErrSelfReview):
+				logger.Error().Err(err).Msg("transition not allowed for user")
+				errorResponse(w, http.StatusForbidden, err.Error())
+			default:
+				logger.Error().Err(err).Msg("failed to transition problem")
+				errorResponse(w, http.StatusInternalServerError, "failed to update problem status")
+			}
+
+			return
+		}
+
+		problem, err := datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		eventType := events.ProblemUpdated
+		if action == db.ProblemActionApprove {
+			eventType = events.ProblemPublished
+		}
+
+		publisher.Publish(events.Event{Type: eventType, BoardID: boardID, Data: problem})
+
+		env := envelope{"problem": problem, "transition": transition}
+
+		if action == db.ProblemActionApprove {
+			duplicates, err := findPublishedDuplicates(datastore, boardID, problemID)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to check for duplicate problems")
+			} else if len(duplicates) > 0 {
+				env["warning"] = duplicateWarning(duplicates)
+				env["duplicates"] = duplicates
+			}
+		}
+
+		err = writeJSON(w, http.StatusOK, env, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+func findPublishedDuplicates(datastore transitionProblemDatastore, boardID, problemID uuid.UUID) ([]db.SimilarProblem, error) {
+	holds, err := datastore.GetProblemHolds(problemID)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return datastore.FindSimilarProblems(boardID, problemID, holds, db.NearDuplicateSimilarity, maxDuplicateMatches) //nolint:wrapcheck
+}
+
+func submitProblemHandler(l *zerolog.Logger, datastore transitionProblemDatastore, publisher eventPublisher) http.HandlerFunc {
+	return transitionProblemHandler(l, datastore, publisher, db.ProblemActionSubmit)
+}
+
+func approveProblemHandler(l *zerolog.Logger, datastore transitionProblemDatastore, publisher eventPublisher) http.HandlerFunc {
+	return transitionProblemHandler(l, datastore, publisher, db.ProblemActionApprove)
+}
+
+func rejectProblemHandler(l *zerolog.Logger, datastore transitionProblemDatastore, publisher eventPublisher) http.HandlerFunc {
+	return transitionProblemHandler(l, datastore, publisher, db.ProblemActionReject)
+}
+
+func archiveProblemHandler(l *zerolog.Logger, datastore transitionProblemDatastore, publisher eventPublisher) http.HandlerFunc {
+	return transitionProblemHandler(l, datastore, publisher, db.ProblemActionArchive)
+}
+
+func getProblemTransitionsHandler(l *zerolog.Logger, datastore getProblemTransitionsDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getProblemTransitions").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		problemID, err := uuid.Parse(params.ByName("problem_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("problem_id", params.ByName("problem_id")).Msg("invalid problem ID")
+			errorResponse(w, http.StatusBadRequest, "invalid problem ID")
+
+			return
+		}
+
+		_, err = datastore.GetProblem(boardID, problemID)
+		if err != nil {
+			if errors.Is(err, db.ErrProblemNotFound) {
+				logger.Error().Err(err).Msg("problem not found")
+				errorResponse(w, http.StatusNotFound, "problem not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get problem")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem")
+
+			return
+		}
+
+		transitions, err := datastore.GetProblemTransitions(problemID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get problem transitions")
+			errorResponse(w, http.StatusInternalServerError, "failed to get problem history")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"transitions": transitions}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type createWebhookDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	CreateWebhook(w *db.Webhook) error
+}
+
+// createWebhookHandler registers a URL to be sent a board's events. The
+// response is the only time the webhook's signing secret is shown.
+func createWebhookHandler(l *zerolog.Logger, datastore createWebhookDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "createWebhook").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		if board.OwnerID != userID {
+			errorResponse(w, http.StatusForbidden, "only the board owner can manage its webhooks")
+			return
+		}
+
+		var input struct {
+			URL      string        `json:"url"`
+			Events   []events.Type `json:"events"`
+			MinGrade *int          `json:"min_grade"`
+		}
+
+		err = readJSON(w, r, &input)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to decode request body")
+			errorResponse(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		webhook := &db.Webhook{
+			BoardID:   boardID,
+			URL:       input.URL,
+			Events:    input.Events,
+			MinGrade:  input.MinGrade,
+			CreatedBy: userID,
+		}
+
+		if errs := webhook.Validate(); errs != nil {
+			logger.Error().Any("validationErrors", errs).Msg("failed to validate webhook")
+			failedValidationResponse(w, errs)
+
+			return
+		}
+
+		err = datastore.CreateWebhook(webhook)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to create webhook")
+			errorResponse(w, http.StatusInternalServerError, "failed to create webhook")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusCreated, envelope{"webhook": webhook}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type getWebhooksDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetWebhooks(boardID uuid.UUID) ([]db.Webhook, error)
+}
+
+func getWebhooksHandler(l *zerolog.Logger, datastore getWebhooksDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getWebhooks").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		if board.OwnerID != userID {
+			errorResponse(w, http.StatusForbidden, "only the board owner can manage its webhooks")
+			return
+		}
+
+		webhooks, err := datastore.GetWebhooks(boardID)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to get webhooks")
+			errorResponse(w, http.StatusInternalServerError, "failed to get webhooks")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"webhooks": webhooks}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
+
+type deleteWebhookDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	DeleteWebhook(boardID, webhookID uuid.UUID) error
+}
+
+// deleteWebhookHandler removes a webhook along with any deliveries still
+// waiting to be sent.
+func deleteWebhookHandler(l *zerolog.Logger, datastore deleteWebhookDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "deleteWebhook").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		webhookID, err := uuid.Parse(params.ByName("webhook_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("webhook_id", params.ByName("webhook_id")).Msg("invalid webhook ID")
+			errorResponse(w, http.StatusBadRequest, "invalid webhook ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		if board.OwnerID != userID {
+			errorResponse(w, http.StatusForbidden, "only the board owner can manage its webhooks")
+			return
+		}
+
+		err = datastore.DeleteWebhook(boardID, webhookID)
+		if err != nil {
+			if errors.Is(err, db.ErrWebhookNotFound) {
+				logger.Error().Err(err).Msg("webhook not found")
+				errorResponse(w, http.StatusNotFound, "webhook not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to delete webhook")
+			errorResponse(w, http.StatusInternalServerError, "failed to delete webhook")
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type getWebhookDeliveriesDatastore interface {
+	GetBoard(id uuid.UUID) (*db.Board, error)
+	GetWebhookDeliveries(boardID, webhookID uuid.UUID, p db.Pagination) ([]db.WebhookDelivery, db.Metadata, error)
+}
+
+// getWebhookDeliveriesHandler pages through the log of what has been sent to
+// a webhook, newest first, including deliveries still being retried and the
+// last error each one hit.
+func getWebhookDeliveriesHandler(l *zerolog.Logger, datastore getWebhookDeliveriesDatastore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := l.With().Str("handler", "getWebhookDeliveries").Logger()
+
+		params := httprouter.ParamsFromContext(r.Context())
+
+		boardID, err := uuid.Parse(params.ByName("board_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("board_id", params.ByName("board_id")).Msg("invalid board ID")
+			errorResponse(w, http.StatusBadRequest, "invalid board ID")
+
+			return
+		}
+
+		webhookID, err := uuid.Parse(params.ByName("webhook_id"))
+		if err != nil {
+			logger.Error().Err(err).Str("webhook_id", params.ByName("webhook_id")).Msg("invalid webhook ID")
+			errorResponse(w, http.StatusBadRequest, "invalid webhook ID")
+
+			return
+		}
+
+		userID, err := readUserID(r)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid user ID")
+			userIDErrorResponse(w, err)
+
+			return
+		}
+
+		qs := r.URL.Query()
+		v := validator.New()
+
+		pagination := db.Pagination{
+			Page:     readInt(qs, "page", 1, v),
+			PageSize: readInt(qs, "page_size", 20, v),
+		}
+
+		for key, message := range pagination.Validate() {
+			v.AddError(key, message)
+		}
+
+		if !v.Valid() {
+			failedValidationResponse(w, v.Errors)
+			return
+		}
+
+		board, err := datastore.GetBoard(boardID)
+		if err != nil {
+			if errors.Is(err, db.ErrBoardNotFound) {
+				logger.Error().Err(err).Msg("board not found")
+				errorResponse(w, http.StatusNotFound, "board not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get board")
+			errorResponse(w, http.StatusInternalServerError, "internal server error")
+
+			return
+		}
+
+		if board.OwnerID != userID {
+			errorResponse(w, http.StatusForbidden, "only the board owner can manage its webhooks")
+			return
+		}
+
+		deliveries, metadata, err := datastore.GetWebhookDeliveries(boardID, webhookID, pagination)
+		if err != nil {
+			if errors.Is(err, db.ErrWebhookNotFound) {
+				logger.Error().Err(err).Msg("webhook not found")
+				errorResponse(w, http.StatusNotFound, "webhook not found")
+
+				return
+			}
+
+			logger.Error().Err(err).Msg("failed to get webhook deliveries")
+			errorResponse(w, http.StatusInternalServerError, "failed to get webhook deliveries")
+
+			return
+		}
+
+		err = writeJSON(w, http.StatusOK, envelope{"deliveries": deliveries, "metadata": metadata}, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to write response")
+			return
+		}
+	}
+}
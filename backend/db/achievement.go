@@ -0,0 +1,353 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AchievementRule is the kind of thing an achievement is awarded for. Each
+// rule reads its threshold from the achievement's Grade or Count.
+type AchievementRule string
+
+const (
+	// AchievementRuleGradeSent is sending a problem of at least Grade.
+	AchievementRuleGradeSent AchievementRule = "grade-sent"
+	// AchievementRuleSends is sending Count different problems.
+	AchievementRuleSends AchievementRule = "sends"
+	// AchievementRuleSessionFlashes is flashing Count problems in one session.
+	AchievementRuleSessionFlashes AchievementRule = "session-flashes"
+	// AchievementRuleCircuits is sending every problem in Count circuits.
+	AchievementRuleCircuits AchievementRule = "circuits"
+)
+
+// Achievement is something a user can earn from their attempt log.
+type Achievement struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Rule        AchievementRule `json:"rule"`
+	Grade       int             `json:"grade,omitempty"`
+	Count       int             `json:"count,omitempty"`
+}
+
+// Achievements is every achievement that can be awarded. Awards are stored by
+// ID, so IDs must never be reused for something else.
+var Achievements = []Achievement{
+	{ID: "first-send", Name: "First Send", Description: "Send your first problem", Rule: AchievementRuleSends, Count: 1},
+	{ID: "sends-100", Name: "Centurion", Description: "Send 100 different problems", Rule: AchievementRuleSends, Count: 100},
+	{ID: "first-v3", Name: "First V3", Description: "Send a V3 or harder", Rule: AchievementRuleGradeSent, Grade: 3},
+	{ID: "first-v5", Name: "First V5", Description: "Send a V5 or harder", Rule: AchievementRuleGradeSent, Grade: 5},
+	{ID: "first-v7", Name: "First V7", Description: "Send a V7 or harder", Rule: AchievementRuleGradeSent, Grade: 7},
+	{ID: "first-v10", Name: "First V10", Description: "Send a V10 or harder", Rule: AchievementRuleGradeSent, Grade: 10},
+	{ID: "session-flashes-10", Name: "Flash Flood", Description: "Flash 10 problems in one session", Rule: AchievementRuleSessionFlashes, Count: 10},
+	{ID: "circuit-complete", Name: "Full Circuit", Description: "Send every problem in a circuit", Rule: AchievementRuleCircuits, Count: 1},
+}
+
+// AchievementProgress is where a user stands just after sending a problem
+// for the first time. Only the fields the achievements still to be awarded
+// need are filled in.
+type AchievementProgress struct {
+	// Grade is the grade of the problem just sent.
+	Grade *int
+	// Sends is how many different problems the user has sent.
+	Sends int
+	// SessionFlashes is how many problems were flashed in the send's session.
+	SessionFlashes int
+	// CompletedCircuits is how many circuits the user has sent every problem
+	// in.
+	CompletedCircuits int
+}
+
+// EvaluateAchievements returns the IDs of the pending achievements that
+// progress earns.
+func EvaluateAchievements(pending []Achievement, progress AchievementProgress) []string {
+	earned := []string{}
+
+	for _, a := range pending {
+		var ok bool
+
+		switch a.Rule {
+		case AchievementRuleGradeSent:
+			ok = progress.Grade != nil && *progress.Grade >= a.Grade
+		case AchievementRuleSends:
+			ok = progress.Sends >= a.Count
+		case AchievementRuleSessionFlashes:
+			ok = progress.SessionFlashes >= a.Count
+		case AchievementRuleCircuits:
+			ok = progress.CompletedCircuits >= a.Count
+		}
+
+		if ok {
+			earned = append(earned, a.ID)
+		}
+	}
+
+	return earned
+}
+
+// UserAchievement is an achievement a user has been awarded.
+type UserAchievement struct {
+	Achievement
+	AwardedAt time.Time `json:"awarded_at"`
+}
+
+// userAchievements pairs awarded IDs with their definitions, dropping any
+// that are no longer defined.
+func userAchievements(awarded map[string]time.Time, order []string) []UserAchievement {
+	achievements := []UserAchievement{}
+
+	for _, id := range order {
+		for _, a := range Achievements {
+			if a.ID == id {
+				achievements = append(achievements, UserAchievement{Achievement: a, AwardedAt: awarded[id]})
+				break
+			}
+		}
+	}
+
+	return achievements
+}
+
+// pendingAchievements returns the achievements a user hasn't been awarded
+// yet, and the highest Count each rule still needs.
+func (d *DB) pendingAchievements(userID uuid.UUID) ([]Achievement, map[AchievementRule]int, error) {
+	rows, err := d.Query(`SELECT achievement_id FROM user_achievements WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error querying achievements: %v", err)
+	}
+	defer rows.Close()
+
+	awarded := make(map[string]bool)
+
+	for rows.Next() {
+		var id string
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning achievement: %v", err)
+		}
+
+		awarded[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating achievements: %v", err)
+	}
+
+	var pending []Achievement
+
+	needed := make(map[AchievementRule]int)
+
+	for _, a := range Achievements {
+		if awarded[a.ID] {
+			continue
+		}
+
+		pending = append(pending, a)
+		needed[a.Rule] = max(needed[a.Rule], a.Count, 1)
+	}
+
+	return pending, needed, nil
+}
+
+// countCompletedCircuits counts the circuits, public or the user's own, that
+// the user has sent every problem in, stopping at limit. When problemID isn't
+// nil only circuits containing that problem are counted.
+func (d *DB) countCompletedCircuits(userID uuid.UUID, problemID *uuid.UUID, limit int) (int, error) {
+	var count int
+
+	err := d.QueryRow(`
+		SELECT COUNT(*)
+		FROM (
+			SELECT 1
+			FROM circuits c
+			WHERE (c.public OR c.owner_id = $1)
+				AND ($2::uuid IS NULL OR c.id IN (SELECT circuit_id FROM circuit_problems WHERE problem_id = $2))
+				AND EXISTS (SELECT 1 FROM circuit_problems cp WHERE cp.circuit_id = c.id)
+				AND NOT EXISTS (
+					SELECT 1
+					FROM circuit_problems cp
+					WHERE cp.circuit_id = c.id
+						AND NOT EXISTS (
+							SELECT 1 FROM attempts a
+							WHERE a.user_id = $1 AND a.problem_id = cp.problem_id AND a.status = 'sent'
+						)
+				)
+			LIMIT $3
+		) completed
+	`, userID, problemID, limit).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting completed circuits: %v", err)
+	}
+
+	return count, nil
+}
+
+// AwardAchievements awards the achievements a newly logged attempt earns and
+// returns them. Only a user's first send of a problem can earn anything, and
+// only the achievements they don't have yet are checked, so the work done is
+// bounded by the attempt's problem, its session and the thresholds still to
+// reach rather than by the user's whole history.
+func (d *DB) AwardAchievements(a *Attempt) ([]UserAchievement, error) {
+	if a.Status != AttemptStatusSent {
+		return []UserAchievement{}, nil
+	}
+
+	var (
+		progress    AchievementProgress
+		earlier     int
+		earlierSent bool
+	)
+
+	err := d.QueryRow(`
+		SELECT
+			(SELECT grade FROM problems WHERE id = $2),
+			COUNT(*),
+			COALESCE(BOOL_OR(status = 'sent'), FALSE)
+		FROM attempts
+		WHERE user_id = $1 AND problem_id = $2
+			AND (attempted_at, id) < ($3::timestamp, $4::uuid)
+	`, a.UserID, a.ProblemID, a.AttemptedAt, a.ID).Scan(&progress.Grade, &earlier, &earlierSent)
+	if err != nil {
+		return nil, fmt.Errorf("error querying earlier attempts: %v", err)
+	}
+
+	if earlierSent {
+		return []UserAchievement{}, nil
+	}
+
+	pending, needed, err := d.pendingAchievements(a.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit, ok := needed[AchievementRuleSends]; ok {
+		err = d.QueryRow(`
+			SELECT COUNT(*)
+			FROM (
+				SELECT DISTINCT problem_id FROM attempts WHERE user_id = $1 AND status = 'sent' LIMIT $2
+			) sent
+		`, a.UserID, limit).Scan(&progress.Sends)
+		if err != nil {
+			return nil, fmt.Errorf("error counting sends: %v", err)
+		}
+	}
+
+	// A flash is a send on the first attempt at a problem, so the session can
+	// only have gained one if this was it
+	if _, ok := needed[AchievementRuleSessionFlashes]; ok && earlier == 0 && a.SessionID != nil {
+		err = d.QueryRow(`
+			SELECT COUNT(*)
+			FROM attempts s
+			WHERE s.session_id = $1 AND s.status = 'sent'
+				AND NOT EXISTS (
+					SELECT 1 FROM attempts e
+					WHERE e.user_id = s.user_id AND e.problem_id = s.problem_id
+						AND (e.attempted_at, e.id) < (s.attempted_at, s.id)
+				)
+		`, a.SessionID).Scan(&progress.SessionFlashes)
+		if err != nil {
+			return nil, fmt.Errorf("error counting session flashes: %v", err)
+		}
+	}
+
+	// The circuits completed can only have changed if this send finished one
+	// of the circuits its problem is in
+	if limit, ok := needed[AchievementRuleCircuits]; ok {
+		finished, err := d.countCompletedCircuits(a.UserID, &a.ProblemID, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if finished > 0 {
+			progress.CompletedCircuits, err = d.countCompletedCircuits(a.UserID, nil, limit)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	earned := EvaluateAchievements(pending, progress)
+	if len(earned) == 0 {
+		return []UserAchievement{}, nil
+	}
+
+	tx, err := d.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %v", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	awarded := make(map[string]time.Time)
+	order := []string{}
+
+	for _, id := range earned {
+		var awardedAt time.Time
+
+		err := tx.QueryRow(`
+			INSERT INTO user_achievements (user_id, achievement_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+			RETURNING awarded_at
+		`, a.UserID, id).Scan(&awardedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error awarding achievement: %v", err)
+		}
+
+		awarded[id] = awardedAt
+		order = append(order, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return userAchievements(awarded, order), nil
+}
+
+// GetUserAchievements returns the achievements a user has been awarded,
+// oldest first.
+func (d *DB) GetUserAchievements(userID uuid.UUID) ([]UserAchievement, error) {
+	rows, err := d.Query(`
+		SELECT achievement_id, awarded_at
+		FROM user_achievements
+		WHERE user_id = $1
+		ORDER BY awarded_at, achievement_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying achievements: %v", err)
+	}
+	defer rows.Close()
+
+	awarded := make(map[string]time.Time)
+	order := []string{}
+
+	for rows.Next() {
+		var (
+			id        string
+			awardedAt time.Time
+		)
+
+		err := rows.Scan(&id, &awardedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning achievement: %v", err)
+		}
+
+		awarded[id] = awardedAt
+		order = append(order, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating achievements: %v", err)
+	}
+
+	return userAchievements(awarded, order), nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvaluateAchievements(t *testing.T) {
+	grade := func(g int) *int { return &g }
+
+	tests := []struct {
+		name     string
+		pending  []Achievement
+		progress AchievementProgress
+		want     []string
+	}{
+		{
+			name:     "nothing pending",
+			progress: AchievementProgress{Grade: grade(10), Sends: 100, SessionFlashes: 10, CompletedCircuits: 1},
+			want:     []string{},
+		},
+		{
+			name:     "first send",
+			pending:  Achievements,
+			progress: AchievementProgress{Sends: 1},
+			want:     []string{"first-send"},
+		},
+		{
+			name:     "grade thresholds up to the grade sent",
+			pending:  Achievements,
+			progress: AchievementProgress{Grade: grade(7), Sends: 2},
+			want:     []string{"first-send", "first-v3", "first-v5", "first-v7"},
+		},
+		{
+			name:     "ungraded sends earn no grade achievements",
+			pending:  Achievements[2:6],
+			progress: AchievementProgress{Sends: 1},
+			want:     []string{},
+		},
+		{
+			name:     "counts below the threshold",
+			pending:  Achievements,
+			progress: AchievementProgress{Sends: 99, SessionFlashes: 9},
+			want:     []string{"first-send"},
+		},
+		{
+			name:     "counts at the threshold",
+			pending:  Achievements,
+			progress: AchievementProgress{Sends: 100, SessionFlashes: 10, CompletedCircuits: 1},
+			want:     []string{"first-send", "sends-100", "session-flashes-10", "circuit-complete"},
+		},
+		{
+			name:     "only pending achievements are returned",
+			pending:  []Achievement{Achievements[1], Achievements[4]},
+			progress: AchievementProgress{Grade: grade(10), Sends: 150},
+			want:     []string{"sends-100", "first-v7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluateAchievements(tt.pending, tt.progress)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
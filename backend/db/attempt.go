@@ -1,15 +1,141 @@
 package db
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+const (
+	AttemptStatusSent      = "sent"
+	AttemptStatusFailed    = "failed"
+	AttemptStatusAttempted = "attempted"
 )
 
 type Attempt struct {
-	ID          uuid.UUID `json:"id"`
-	UserID      uuid.UUID `json:"user_id"`
-	ProblemID   uuid.UUID `json:"problem_id"`
-	Status      string    `json:"status"`
-	AttemptedAt time.Time `json:"attempted_at"`
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	ProblemID   uuid.UUID  `json:"problem_id"`
+	Status      string     `json:"status"`
+	Mirrored    bool       `json:"mirrored"`
+	Zone        bool       `json:"zone"`
+	SessionID   *uuid.UUID `json:"session_id"`
+	AttemptedAt time.Time  `json:"attempted_at"`
+}
+
+func (a Attempt) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(
+		validator.PermittedValue(a.Status, AttemptStatusSent, AttemptStatusFailed, AttemptStatusAttempted),
+		"status", "must be one of sent, failed or attempted",
+	)
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// CreateAttempt logs an attempt in the user's current session. A send takes
+// the problem off the user's project list. The board's webhooks are told
+// about the attempt, and separately about sends.
+func (d *DB) CreateAttempt(a *Attempt) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	sessionID, err := currentSession(tx, a.UserID)
+	if err != nil {
+		return err
+	}
+
+	a.SessionID = &sessionID
+
+	err = tx.QueryRow(`
+		INSERT INTO attempts (user_id, problem_id, status, mirrored, zone, session_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, attempted_at
+	`, a.UserID, a.ProblemID, a.Status, a.Mirrored, a.Zone, a.SessionID).Scan(&a.ID, &a.AttemptedAt)
+	if err != nil {
+		return fmt.Errorf("error creating attempt: %v", err)
+	}
+
+	if a.Status == AttemptStatusSent {
+		_, err = tx.Exec(`DELETE FROM projects WHERE user_id = $1 AND problem_id = $2`, a.UserID, a.ProblemID)
+		if err != nil {
+			return fmt.Errorf("error removing project: %v", err)
+		}
+	}
+
+	var problem Problem
+
+	err = scanProblem(tx.QueryRow(`SELECT `+problemColumns+` FROM problems WHERE id = $1`, a.ProblemID), &problem)
+	if err != nil {
+		return fmt.Errorf("error querying problem: %v", err)
+	}
+
+	// Webhooks get the problem along with the attempt, so they can say what
+	// was climbed without calling back
+	data := map[string]any{"attempt": a, "problem": problem}
+
+	err = enqueueWebhooks(tx, events.Event{Type: events.AttemptLogged, BoardID: problem.BoardID, Data: data}, problem.Grade)
+	if err != nil {
+		return err
+	}
+
+	if a.Status == AttemptStatusSent {
+		err = enqueueWebhooks(tx, events.Event{Type: events.ProblemSent, BoardID: problem.BoardID, Data: data}, problem.Grade)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// GetAttempts returns the attempts logged on a problem, newest first. When
+// userID isn't nil only that user's attempts are returned.
+func (d *DB) GetAttempts(problemID uuid.UUID, userID *uuid.UUID) ([]Attempt, error) {
+	rows, err := d.Query(`
+		SELECT id, user_id, problem_id, status, mirrored, zone, session_id, attempted_at
+		FROM attempts
+		WHERE problem_id = $1 AND ($2::uuid IS NULL OR user_id = $2)
+		ORDER BY attempted_at DESC
+	`, problemID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attempts: %v", err)
+	}
+	defer rows.Close()
+
+	var attempts []Attempt
+
+	for rows.Next() {
+		var a Attempt
+
+		err := rows.Scan(&a.ID, &a.UserID, &a.ProblemID, &a.Status, &a.Mirrored, &a.Zone, &a.SessionID, &a.AttemptedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning attempt: %v", err)
+		}
+
+		attempts = append(attempts, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attempts: %v", err)
+	}
+
+	return attempts, nil
 }
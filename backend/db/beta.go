@@ -0,0 +1,184 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type Limb string
+
+const (
+	LimbLeftHand  Limb = "left_hand"
+	LimbRightHand Limb = "right_hand"
+	LimbLeftFoot  Limb = "left_foot"
+	LimbRightFoot Limb = "right_foot"
+)
+
+// BetaMove places one limb on one hold. Moves are climbed in order of
+// Position, starting at zero.
+type BetaMove struct {
+	Position int       `json:"position"`
+	Limb     Limb      `json:"limb"`
+	HoldID   uuid.UUID `json:"hold_id"`
+}
+
+// Beta is one user's intended sequence for a problem.
+type Beta struct {
+	ID          uuid.UUID  `json:"id"`
+	ProblemID   uuid.UUID  `json:"problem_id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Description string     `json:"description"`
+	Moves       []BetaMove `json:"moves"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Validate checks the beta's moves against the holds that make up its problem.
+func (b Beta) Validate(problemHolds []ProblemHold) map[string]string {
+	v := validator.New()
+
+	v.Check(len(b.Moves) > 0, "moves", "must contain at least one move")
+
+	onProblem := make(map[uuid.UUID]bool, len(problemHolds))
+	for _, h := range problemHolds {
+		onProblem[h.HoldID] = true
+	}
+
+	for i, m := range b.Moves {
+		v.Check(
+			validator.PermittedValue(m.Limb, LimbLeftHand, LimbRightHand, LimbLeftFoot, LimbRightFoot),
+			fmt.Sprintf("moves[%d].limb", i),
+			"must be one of left_hand, right_hand, left_foot or right_foot",
+		)
+		v.Check(onProblem[m.HoldID], fmt.Sprintf("moves[%d].hold_id", i), "must be a hold on the problem")
+	}
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+func (d *DB) CreateBeta(beta *Beta) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	err = tx.QueryRow(`
+		INSERT INTO betas (problem_id, user_id, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, beta.ProblemID, beta.UserID, beta.Description).Scan(&beta.ID, &beta.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "unique_beta_per_user" {
+			return ErrDuplicateBeta
+		}
+
+		return fmt.Errorf("error creating beta: %v", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO beta_moves (beta_id, position, limb, hold_id)
+		VALUES ($1, $2, $3, $4)
+	`)
+	if err != nil {
+		return fmt.Errorf("error preparing statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for i := range beta.Moves {
+		beta.Moves[i].Position = i
+
+		_, err = stmt.Exec(beta.ID, i, beta.Moves[i].Limb, beta.Moves[i].HoldID)
+		if err != nil {
+			return fmt.Errorf("error creating beta move: %v", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetBetas(problemID uuid.UUID) ([]Beta, error) {
+	rows, err := d.Query(`
+		SELECT b.id, b.problem_id, b.user_id, b.description, b.created_at, m.position, m.limb, m.hold_id
+		FROM betas b
+		JOIN beta_moves m ON m.beta_id = b.id
+		WHERE b.problem_id = $1
+		ORDER BY b.created_at, b.id, m.position
+	`, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying betas: %v", err)
+	}
+	defer rows.Close()
+
+	var betas []Beta
+
+	for rows.Next() {
+		var b Beta
+
+		var m BetaMove
+
+		err := rows.Scan(&b.ID, &b.ProblemID, &b.UserID, &b.Description, &b.CreatedAt, &m.Position, &m.Limb, &m.HoldID)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning beta: %v", err)
+		}
+
+		// Rows are ordered by beta, so a new ID starts a new beta
+		if len(betas) == 0 || betas[len(betas)-1].ID != b.ID {
+			betas = append(betas, b)
+		}
+
+		last := &betas[len(betas)-1]
+		last.Moves = append(last.Moves, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating betas: %v", err)
+	}
+
+	return betas, nil
+}
+
+func (d *DB) DeleteBeta(problemID, betaID, userID uuid.UUID) error {
+	var ownerID uuid.UUID
+
+	err := d.QueryRow(`
+		SELECT user_id
+		FROM betas
+		WHERE id = $1 AND problem_id = $2
+	`, betaID, problemID).Scan(&ownerID)
+
+	if err == sql.ErrNoRows {
+		return ErrBetaNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying beta: %v", err)
+	}
+
+	if ownerID != userID {
+		return ErrNotBetaAuthor
+	}
+
+	_, err = d.Exec(`DELETE FROM betas WHERE id = $1`, betaID)
+	if err != nil {
+		return fmt.Errorf("error deleting beta: %v", err)
+	}
+
+	return nil
+}
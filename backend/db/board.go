@@ -10,13 +10,19 @@ import (
 	"github.com/vizvim/bloc/backend/validator"
 )
 
+// Board is a climbing wall. Boards in an organization can be seen by its
+// members, and public boards by everyone.
 type Board struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Image     []byte    `json:"image"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	Version   int       `json:"version"`
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	Image          []byte     `json:"image"`
+	OwnerID        uuid.UUID  `json:"ownerID"`
+	OrganizationID *uuid.UUID `json:"organizationID"`
+	Public         bool       `json:"public"`
+	Symmetric      bool       `json:"symmetric"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	Version        int        `json:"version"`
 }
 
 func (b Board) Validate() map[string]string {
@@ -34,11 +40,11 @@ func (b Board) Validate() map[string]string {
 
 func (d *DB) CreateBoard(ctx context.Context, b *Board) error {
 	query := `
-    INSERT INTO boards (name, image) 
-    VALUES ($1, $2)
+    INSERT INTO boards (name, image, owner_id, organization_id, public) 
+    VALUES ($1, $2, $3, $4, $5)
     RETURNING id, created_at, updated_at, version`
 
-	args := []any{b.Name, b.Image}
+	args := []any{b.Name, b.Image, b.OwnerID, b.OrganizationID, b.Public}
 
 	err := d.QueryRowContext(ctx, query, args...).Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt, &b.Version)
 	if err != nil {
@@ -51,10 +57,13 @@ func (d *DB) CreateBoard(ctx context.Context, b *Board) error {
 func (d *DB) GetBoard(id uuid.UUID) (*Board, error) {
 	var board Board
 	err := d.QueryRow(`
-		SELECT id, name, image, created_at, updated_at, version
+		SELECT id, name, image, owner_id, organization_id, public, symmetric, created_at, updated_at, version
 		FROM boards
 		WHERE id = $1
-	`, id).Scan(&board.ID, &board.Name, &board.Image, &board.CreatedAt, &board.UpdatedAt, &board.Version)
+	`, id).Scan(
+		&board.ID, &board.Name, &board.Image, &board.OwnerID, &board.OrganizationID, &board.Public,
+		&board.Symmetric, &board.CreatedAt, &board.UpdatedAt, &board.Version,
+	)
 
 	if err == sql.ErrNoRows {
 		return nil, ErrBoardNotFound
@@ -67,12 +76,45 @@ func (d *DB) GetBoard(id uuid.UUID) (*Board, error) {
 	return &board, nil
 }
 
-func (d *DB) GetAllBoards(ctx context.Context) ([]Board, error) {
-	query := `SELECT id, name, image, created_at, updated_at, version FROM boards`
+// UpdateBoardAccess saves who owns a board, which organization it belongs to
+// and whether it's public.
+func (d *DB) UpdateBoardAccess(ctx context.Context, b *Board) error {
+	query := `
+	UPDATE boards
+	SET owner_id = $1, organization_id = $2, public = $3, updated_at = NOW(), version = version + 1
+	WHERE id = $4
+	RETURNING updated_at, version`
+
+	args := []any{b.OwnerID, b.OrganizationID, b.Public, b.ID}
+
+	err := d.QueryRowContext(ctx, query, args...).Scan(&b.UpdatedAt, &b.Version)
+	if err == sql.ErrNoRows {
+		return ErrBoardNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error updating board: %v", err)
+	}
+
+	return nil
+}
+
+// GetAllBoards returns the boards a user can see: public boards, their own and
+// those of the organizations they belong to. Moderators see every board,
+// including ones they've hidden.
+func (d *DB) GetAllBoards(ctx context.Context, userID uuid.UUID, moderator bool) ([]Board, error) {
+	query := `
+	SELECT id, name, image, owner_id, organization_id, public, symmetric, created_at, updated_at, version
+	FROM boards
+	WHERE $2 OR (hidden_at IS NULL AND (
+		public
+		OR owner_id = $1
+		OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = $1)
+	))`
 
 	var boards []Board
 
-	rows, err := d.QueryContext(ctx, query)
+	rows, err := d.QueryContext(ctx, query, userID, moderator)
 	if err != nil {
 		return nil, fmt.Errorf("error getting boards: %v", err)
 	}
@@ -82,7 +124,10 @@ func (d *DB) GetAllBoards(ctx context.Context) ([]Board, error) {
 	for rows.Next() {
 		var board Board
 
-		err := rows.Scan(&board.ID, &board.Name, &board.Image, &board.CreatedAt, &board.UpdatedAt, &board.Version)
+		err := rows.Scan(
+			&board.ID, &board.Name, &board.Image, &board.OwnerID, &board.OrganizationID, &board.Public,
+			&board.Symmetric, &board.CreatedAt, &board.UpdatedAt, &board.Version,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning board: %v", err)
 		}
@@ -0,0 +1,387 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+// Circuit is an ordered list of problems to climb in one go, such as a warmup.
+// Its problems can come from any number of boards. Private circuits are only
+// visible to their owner; public ones are listed for everyone.
+type Circuit struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	OwnerID     uuid.UUID   `json:"owner_id"`
+	Public      bool        `json:"public"`
+	ProblemIDs  []uuid.UUID `json:"problem_ids"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// CircuitProgress is how far a user has got with one problem in a circuit.
+type CircuitProgress struct {
+	Attempts int        `json:"attempts"`
+	Sent     bool       `json:"sent"`
+	SentAt   *time.Time `json:"sent_at"`
+}
+
+func (c Circuit) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(c.Name != "", "name", "must be provided")
+	v.Check(len(c.Name) <= 100, "name", "must not be more than 100 characters long")
+	v.Check(len(c.ProblemIDs) > 0, "problem_ids", "must contain at least one problem")
+
+	seen := make(map[uuid.UUID]bool, len(c.ProblemIDs))
+
+	for i, id := range c.ProblemIDs {
+		v.Check(!seen[id], fmt.Sprintf("problem_ids[%d]", i), "must not appear more than once")
+		seen[id] = true
+	}
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// VisibleTo reports whether a user may see the circuit.
+func (c Circuit) VisibleTo(userID uuid.UUID) bool {
+	return c.Public || c.OwnerID == userID
+}
+
+func (d *DB) CreateCircuit(c *Circuit) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	err = tx.QueryRow(`
+		INSERT INTO circuits (name, description, owner_id, public)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, c.Name, c.Description, c.OwnerID, c.Public).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating circuit: %v", err)
+	}
+
+	err = insertCircuitProblems(tx, c)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateCircuit replaces a circuit's details and problems. Only its owner can
+// change it.
+func (d *DB) UpdateCircuit(c *Circuit, userID uuid.UUID) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	err = tx.QueryRow(`
+		SELECT owner_id, created_at
+		FROM circuits
+		WHERE id = $1
+		FOR UPDATE
+	`, c.ID).Scan(&c.OwnerID, &c.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return ErrCircuitNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying circuit: %v", err)
+	}
+
+	if c.OwnerID != userID {
+		return ErrNotCircuitOwner
+	}
+
+	err = tx.QueryRow(`
+		UPDATE circuits
+		SET name = $1, description = $2, public = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING updated_at
+	`, c.Name, c.Description, c.Public, c.ID).Scan(&c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error updating circuit: %v", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM circuit_problems WHERE circuit_id = $1`, c.ID)
+	if err != nil {
+		return fmt.Errorf("error deleting circuit problems: %v", err)
+	}
+
+	err = insertCircuitProblems(tx, c)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// checkAddableProblems makes sure problems can be added to something
+// ownerID shares: each must be published and on a board the owner can see,
+// and on a public board when everyone can see what they're added to.
+func checkAddableProblems(tx *sql.Tx, problemIDs []uuid.UUID, ownerID uuid.UUID, public bool) error {
+	ids := make([]string, len(problemIDs))
+	for i, id := range problemIDs {
+		ids[i] = id.String()
+	}
+
+	var visible, published int
+
+	err := tx.QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE p.status = 'PUBLISHED')
+		FROM problems p
+		JOIN boards b ON b.id = p.board_id
+		WHERE p.id = ANY($1::uuid[])
+			AND board_visible_to(p.board_id, $2)
+			AND (b.public OR NOT $3)
+	`, pq.Array(ids), ownerID, public).Scan(&visible, &published)
+	if err != nil {
+		return fmt.Errorf("error checking problems: %v", err)
+	}
+
+	if visible != len(problemIDs) {
+		return ErrPrivateProblem
+	}
+
+	if published != len(problemIDs) {
+		return ErrUnpublishedProblem
+	}
+
+	return nil
+}
+
+// insertCircuitProblems adds problems to a circuit in order. Every problem
+// must be published, though it may be archived later without leaving the
+// circuit, and visible to everyone who can see the circuit.
+func insertCircuitProblems(tx *sql.Tx, c *Circuit) error {
+	err := checkAddableProblems(tx, c.ProblemIDs, c.OwnerID, c.Public)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO circuit_problems (circuit_id, position, problem_id)
+		VALUES ($1, $2, $3)
+	`)
+	if err != nil {
+		return fmt.Errorf("error preparing statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for i, id := range c.ProblemIDs {
+		_, err = stmt.Exec(c.ID, i, id)
+		if err != nil {
+			return fmt.Errorf("error adding problem to circuit: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (d *DB) DeleteCircuit(circuitID, userID uuid.UUID) error {
+	var ownerID uuid.UUID
+
+	err := d.QueryRow(`SELECT owner_id FROM circuits WHERE id = $1`, circuitID).Scan(&ownerID)
+
+	if err == sql.ErrNoRows {
+		return ErrCircuitNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying circuit: %v", err)
+	}
+
+	if ownerID != userID {
+		return ErrNotCircuitOwner
+	}
+
+	_, err = d.Exec(`DELETE FROM circuits WHERE id = $1`, circuitID)
+	if err != nil {
+		return fmt.Errorf("error deleting circuit: %v", err)
+	}
+
+	return nil
+}
+
+const circuitColumns = `
+	c.id, c.name, c.description, c.owner_id, c.public, c.created_at, c.updated_at,
+	ARRAY(SELECT problem_id::text FROM circuit_problems WHERE circuit_id = c.id ORDER BY position)`
+
+func scanCircuit(s rowScanner, c *Circuit) error {
+	var ids []string
+
+	err := s.Scan(&c.ID, &c.Name, &c.Description, &c.OwnerID, &c.Public, &c.CreatedAt, &c.UpdatedAt, pq.Array(&ids))
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	c.ProblemIDs = make([]uuid.UUID, len(ids))
+
+	for i, id := range ids {
+		c.ProblemIDs[i], err = uuid.Parse(id)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	return nil
+}
+
+func (d *DB) GetCircuit(circuitID uuid.UUID) (*Circuit, error) {
+	var c Circuit
+
+	err := scanCircuit(d.QueryRow(`
+		SELECT `+circuitColumns+`
+		FROM circuits c
+		WHERE c.id = $1
+	`, circuitID), &c)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrCircuitNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error querying circuit: %v", err)
+	}
+
+	return &c, nil
+}
+
+// GetCircuits returns the public circuits and the user's own private ones,
+// newest first. When ownerID isn't nil only that user's circuits are returned.
+func (d *DB) GetCircuits(userID uuid.UUID, ownerID *uuid.UUID) ([]Circuit, error) {
+	rows, err := d.Query(`
+		SELECT `+circuitColumns+`
+		FROM circuits c
+		WHERE (c.public OR c.owner_id = $1)
+			AND ($2::uuid IS NULL OR c.owner_id = $2)
+		ORDER BY c.created_at DESC
+	`, userID, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying circuits: %v", err)
+	}
+	defer rows.Close()
+
+	var circuits []Circuit
+
+	for rows.Next() {
+		var c Circuit
+
+		err := scanCircuit(rows, &c)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning circuit: %v", err)
+		}
+
+		circuits = append(circuits, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating circuits: %v", err)
+	}
+
+	return circuits, nil
+}
+
+// GetCircuitProblems returns a circuit's problems in order, leaving out any
+// on boards the user can't see and, unless they're a moderator, any that have
+// been hidden.
+func (d *DB) GetCircuitProblems(circuitID, userID uuid.UUID) ([]Problem, error) {
+	rows, err := d.Query(`
+		SELECT `+problemColumns+`
+		FROM circuit_problems cp
+		JOIN problems ON problems.id = cp.problem_id
+		WHERE cp.circuit_id = $1 AND board_visible_to(problems.board_id, $2)
+			AND (problems.hidden_at IS NULL OR EXISTS (SELECT 1 FROM moderators WHERE user_id = $2))
+		ORDER BY cp.position
+	`, circuitID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying circuit problems: %v", err)
+	}
+	defer rows.Close()
+
+	var problems []Problem
+
+	for rows.Next() {
+		var p Problem
+
+		err := scanProblem(rows, &p)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning problem: %v", err)
+		}
+
+		problems = append(problems, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating circuit problems: %v", err)
+	}
+
+	return problems, nil
+}
+
+// GetCircuitProgress summarises a user's attempts on each problem in a circuit.
+// Problems they haven't tried are missing from the map.
+func (d *DB) GetCircuitProgress(circuitID, userID uuid.UUID) (map[uuid.UUID]CircuitProgress, error) {
+	rows, err := d.Query(`
+		SELECT a.problem_id, COUNT(*), MIN(a.attempted_at) FILTER (WHERE a.status = 'sent')
+		FROM circuit_problems cp
+		JOIN attempts a ON a.problem_id = cp.problem_id
+		WHERE cp.circuit_id = $1 AND a.user_id = $2
+		GROUP BY a.problem_id
+	`, circuitID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying circuit progress: %v", err)
+	}
+	defer rows.Close()
+
+	progress := make(map[uuid.UUID]CircuitProgress)
+
+	for rows.Next() {
+		var (
+			problemID uuid.UUID
+			p         CircuitProgress
+		)
+
+		err := rows.Scan(&problemID, &p.Attempts, &p.SentAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning circuit progress: %v", err)
+		}
+
+		p.Sent = p.SentAt != nil
+		progress[problemID] = p
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating circuit progress: %v", err)
+	}
+
+	return progress, nil
+}
@@ -0,0 +1,284 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+// MaxCommentLength is the longest comment body allowed, in bytes.
+const MaxCommentLength = 2000
+
+// Comment is a post in the discussion on a problem. Replies have a ParentID
+// and are nested under their parent when comments are listed. Deleted
+// comments stay in the thread without their body, as do hidden comments for
+// anyone who can't moderate them.
+type Comment struct {
+	ID        uuid.UUID  `json:"id"`
+	ProblemID uuid.UUID  `json:"problem_id"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	AuthorID  uuid.UUID  `json:"author_id"`
+	Body      string     `json:"body"`
+	Deleted   bool       `json:"deleted"`
+	Hidden    bool       `json:"hidden"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	Replies   []*Comment `json:"replies"`
+}
+
+func (c Comment) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(strings.TrimSpace(c.Body) != "", "body", "must be provided")
+	v.Check(len(c.Body) <= MaxCommentLength, "body", fmt.Sprintf("must not be more than %d bytes long", MaxCommentLength))
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// CreateComment posts a comment on a problem on the board, replying to
+// ParentID when it is set. The board's webhooks are told about it.
+func (d *DB) CreateComment(boardID uuid.UUID, c *Comment) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	var grade *int
+
+	err = tx.QueryRow(`SELECT grade FROM problems WHERE id = $1 AND board_id = $2`, c.ProblemID, boardID).Scan(&grade)
+	if err == sql.ErrNoRows {
+		return ErrProblemNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying problem: %v", err)
+	}
+
+	if c.ParentID != nil {
+		var deleted bool
+
+		err = tx.QueryRow(`
+			SELECT deleted_at IS NOT NULL
+			FROM comments
+			WHERE id = $1 AND problem_id = $2
+		`, c.ParentID, c.ProblemID).Scan(&deleted)
+
+		if err == sql.ErrNoRows {
+			return ErrParentCommentNotFound
+		}
+
+		if err != nil {
+			return fmt.Errorf("error querying parent comment: %v", err)
+		}
+
+		if deleted {
+			return ErrCommentDeleted
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO comments (problem_id, parent_id, author_id, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, c.ProblemID, c.ParentID, c.AuthorID, c.Body).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating comment: %v", err)
+	}
+
+	c.Replies = []*Comment{}
+
+	err = enqueueWebhooks(tx, events.Event{Type: events.CommentCreated, BoardID: boardID, Data: c}, grade)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateComment replaces the body of a comment. Only its author can edit it.
+func (d *DB) UpdateComment(problemID uuid.UUID, c *Comment, editorID uuid.UUID) error {
+	var deleted bool
+
+	err := d.QueryRow(`
+		SELECT author_id, deleted_at IS NOT NULL
+		FROM comments
+		WHERE id = $1 AND problem_id = $2
+	`, c.ID, problemID).Scan(&c.AuthorID, &deleted)
+
+	if err == sql.ErrNoRows {
+		return ErrCommentNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying comment: %v", err)
+	}
+
+	if deleted {
+		return ErrCommentDeleted
+	}
+
+	if c.AuthorID != editorID {
+		return ErrNotCommentAuthor
+	}
+
+	err = d.QueryRow(`
+		UPDATE comments
+		SET body = $1, edited_at = NOW()
+		WHERE id = $2
+		RETURNING problem_id, parent_id, created_at, edited_at
+	`, c.Body, c.ID).Scan(&c.ProblemID, &c.ParentID, &c.CreatedAt, &c.EditedAt)
+	if err != nil {
+		return fmt.Errorf("error updating comment: %v", err)
+	}
+
+	c.Replies = []*Comment{}
+
+	return nil
+}
+
+// DeleteComment removes the body of a comment, leaving its replies in place.
+// Comments can be deleted by their author, or moderated by the problem's
+// setter or the board's owner.
+func (d *DB) DeleteComment(problemID, commentID, userID uuid.UUID) error {
+	var authorID, setterID, ownerID uuid.UUID
+
+	err := d.QueryRow(`
+		SELECT c.author_id, p.setter_id, b.owner_id
+		FROM comments c
+		JOIN problems p ON p.id = c.problem_id
+		JOIN boards b ON b.id = p.board_id
+		WHERE c.id = $1 AND c.problem_id = $2 AND c.deleted_at IS NULL
+	`, commentID, problemID).Scan(&authorID, &setterID, &ownerID)
+
+	if err == sql.ErrNoRows {
+		return ErrCommentNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying comment: %v", err)
+	}
+
+	if userID != authorID && userID != setterID && userID != ownerID {
+		return ErrNotCommentModerator
+	}
+
+	_, err = d.Exec(`
+		UPDATE comments
+		SET body = '', deleted_at = NOW(), deleted_by = $2
+		WHERE id = $1
+	`, commentID, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting comment: %v", err)
+	}
+
+	return nil
+}
+
+// GetComments returns the threads on a problem, oldest first, with each
+// comment's replies nested under it. Hidden comments only keep their body
+// when includeHidden is set.
+func (d *DB) GetComments(problemID uuid.UUID, includeHidden bool) ([]*Comment, error) {
+	rows, err := d.Query(`
+		SELECT
+			id, problem_id, parent_id, author_id,
+			CASE WHEN hidden_at IS NULL OR $2 THEN body ELSE '' END,
+			deleted_at IS NOT NULL, hidden_at IS NOT NULL, created_at, edited_at
+		FROM comments
+		WHERE problem_id = $1
+		ORDER BY created_at, id
+	`, problemID, includeHidden)
+	if err != nil {
+		return nil, fmt.Errorf("error querying comments: %v", err)
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+
+	for rows.Next() {
+		c := &Comment{Replies: []*Comment{}}
+
+		err := rows.Scan(&c.ID, &c.ProblemID, &c.ParentID, &c.AuthorID, &c.Body, &c.Deleted, &c.Hidden, &c.CreatedAt, &c.EditedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning comment: %v", err)
+		}
+
+		comments = append(comments, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comments: %v", err)
+	}
+
+	byID := make(map[uuid.UUID]*Comment, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = c
+	}
+
+	threads := []*Comment{}
+
+	for _, c := range comments {
+		if c.ParentID == nil {
+			threads = append(threads, c)
+			continue
+		}
+
+		parent := byID[*c.ParentID]
+		parent.Replies = append(parent.Replies, c)
+	}
+
+	return threads, nil
+}
+
+// GetCommentCounts returns how many comments, not counting deleted or hidden
+// ones, each problem on a board has. Problems without comments are left out.
+func (d *DB) GetCommentCounts(boardID uuid.UUID) (map[uuid.UUID]int, error) {
+	rows, err := d.Query(`
+		SELECT c.problem_id, COUNT(*)
+		FROM comments c
+		JOIN problems p ON p.id = c.problem_id
+		WHERE p.board_id = $1 AND c.deleted_at IS NULL AND c.hidden_at IS NULL
+		GROUP BY c.problem_id
+	`, boardID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying comment counts: %v", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+
+	for rows.Next() {
+		var (
+			problemID uuid.UUID
+			count     int
+		)
+
+		err := rows.Scan(&problemID, &count)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning comment count: %v", err)
+		}
+
+		counts[problemID] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comment counts: %v", err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,474 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type CompetitionFormat string
+
+const (
+	// CompetitionFormatIFSC ranks by tops, then zones, then the fewest
+	// attempts taken to reach them.
+	CompetitionFormatIFSC CompetitionFormat = "ifsc"
+	// CompetitionFormatPoints scores each problem topped with its points.
+	CompetitionFormatPoints CompetitionFormat = "points"
+	// CompetitionFormatFlashBonus scores like points, plus the competition's
+	// flash bonus for each problem topped on the first attempt.
+	CompetitionFormatFlashBonus CompetitionFormat = "flash_bonus"
+)
+
+// DefaultCompetitionPoints is what a problem is worth when the organiser
+// doesn't say.
+const DefaultCompetitionPoints = 100
+
+type CompetitionProblem struct {
+	ProblemID uuid.UUID `json:"problem_id"`
+	Points    int       `json:"points"`
+}
+
+// Competition is a set of problems that registered competitors score on with
+// the attempts they log between StartsAt and EndsAt.
+type Competition struct {
+	ID         uuid.UUID            `json:"id"`
+	Name       string               `json:"name"`
+	OwnerID    uuid.UUID            `json:"owner_id"`
+	Format     CompetitionFormat    `json:"format"`
+	FlashBonus int                  `json:"flash_bonus"`
+	StartsAt   time.Time            `json:"starts_at"`
+	EndsAt     time.Time            `json:"ends_at"`
+	Problems   []CompetitionProblem `json:"problems"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+func (c Competition) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(c.Name != "", "name", "must be provided")
+	v.Check(len(c.Name) <= 255, "name", "must not be more than 255 bytes long")
+	v.Check(
+		validator.PermittedValue(c.Format, CompetitionFormatIFSC, CompetitionFormatPoints, CompetitionFormatFlashBonus),
+		"format", "must be one of ifsc, points or flash_bonus",
+	)
+	v.Check(c.FlashBonus >= 0, "flash_bonus", "must not be negative")
+	v.Check(!c.StartsAt.IsZero(), "starts_at", "must be provided")
+	v.Check(c.EndsAt.After(c.StartsAt), "ends_at", "must be after starts_at")
+	v.Check(len(c.Problems) > 0, "problems", "must contain at least one problem")
+
+	seen := make(map[uuid.UUID]bool, len(c.Problems))
+
+	for i, p := range c.Problems {
+		key := fmt.Sprintf("problems[%d]", i)
+
+		v.Check(!seen[p.ProblemID], key+".problem_id", "must not appear more than once")
+		v.Check(p.Points >= 0, key+".points", "must not be negative")
+
+		seen[p.ProblemID] = true
+	}
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// CompetitionAttempt is an attempt a competitor made during a competition.
+type CompetitionAttempt struct {
+	UserID      uuid.UUID
+	ProblemID   uuid.UUID
+	Status      string
+	Zone        bool
+	AttemptedAt time.Time
+}
+
+// ProblemResult is how a competitor did on one problem. Attempt counts are the
+// attempts taken to first reach the top or zone, and zero when they didn't.
+type ProblemResult struct {
+	ProblemID    uuid.UUID `json:"problem_id"`
+	Attempts     int       `json:"attempts"`
+	Top          bool      `json:"top"`
+	TopAttempts  int       `json:"top_attempts"`
+	Zone         bool      `json:"zone"`
+	ZoneAttempts int       `json:"zone_attempts"`
+	Points       int       `json:"points"`
+}
+
+type CompetitionResult struct {
+	Rank         int             `json:"rank"`
+	UserID       uuid.UUID       `json:"user_id"`
+	Tops         int             `json:"tops"`
+	Zones        int             `json:"zones"`
+	TopAttempts  int             `json:"top_attempts"`
+	ZoneAttempts int             `json:"zone_attempts"`
+	Points       int             `json:"points"`
+	Problems     []ProblemResult `json:"problems"`
+}
+
+// ScoreCompetition ranks competitors from their attempts, which must be oldest
+// first. A top also counts as reaching the zone. Competitors who score the same
+// share a rank.
+func ScoreCompetition(c Competition, competitors []uuid.UUID, attempts []CompetitionAttempt) []CompetitionResult {
+	index := make(map[uuid.UUID]int, len(c.Problems))
+	for i, p := range c.Problems {
+		index[p.ProblemID] = i
+	}
+
+	results := make([]CompetitionResult, len(competitors))
+	byUser := make(map[uuid.UUID]*CompetitionResult, len(competitors))
+
+	for i, userID := range competitors {
+		results[i] = CompetitionResult{UserID: userID, Problems: make([]ProblemResult, len(c.Problems))}
+
+		for j, p := range c.Problems {
+			results[i].Problems[j].ProblemID = p.ProblemID
+		}
+
+		byUser[userID] = &results[i]
+	}
+
+	for _, a := range attempts {
+		result, ok := byUser[a.UserID]
+		if !ok {
+			continue
+		}
+
+		i, ok := index[a.ProblemID]
+		if !ok {
+			continue
+		}
+
+		pr := &result.Problems[i]
+		pr.Attempts++
+
+		top := a.Status == AttemptStatusSent
+
+		if (top || a.Zone) && !pr.Zone {
+			pr.Zone = true
+			pr.ZoneAttempts = pr.Attempts
+		}
+
+		if top && !pr.Top {
+			pr.Top = true
+			pr.TopAttempts = pr.Attempts
+		}
+	}
+
+	for i := range results {
+		r := &results[i]
+
+		for j := range r.Problems {
+			pr := &r.Problems[j]
+
+			if pr.Zone {
+				r.Zones++
+				r.ZoneAttempts += pr.ZoneAttempts
+			}
+
+			if !pr.Top {
+				continue
+			}
+
+			r.Tops++
+			r.TopAttempts += pr.TopAttempts
+			pr.Points = c.Problems[j].Points
+
+			if c.Format == CompetitionFormatFlashBonus && pr.TopAttempts == 1 {
+				pr.Points += c.FlashBonus
+			}
+
+			r.Points += pr.Points
+		}
+	}
+
+	// compare orders two results, returning a negative number when a ranks
+	// above b and zero when they tie.
+	compare := func(a, b CompetitionResult) int {
+		if c.Format == CompetitionFormatIFSC {
+			switch {
+			case a.Tops != b.Tops:
+				return b.Tops - a.Tops
+			case a.Zones != b.Zones:
+				return b.Zones - a.Zones
+			case a.TopAttempts != b.TopAttempts:
+				return a.TopAttempts - b.TopAttempts
+			default:
+				return a.ZoneAttempts - b.ZoneAttempts
+			}
+		}
+
+		if a.Points != b.Points {
+			return b.Points - a.Points
+		}
+
+		return a.TopAttempts - b.TopAttempts
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return compare(results[i], results[j]) < 0 })
+
+	for i := range results {
+		results[i].Rank = i + 1
+		if i > 0 && compare(results[i-1], results[i]) == 0 {
+			results[i].Rank = results[i-1].Rank
+		}
+	}
+
+	return results
+}
+
+func (d *DB) CreateCompetition(c *Competition) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	err = tx.QueryRow(`
+		INSERT INTO competitions (name, owner_id, format, flash_bonus, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, c.Name, c.OwnerID, c.Format, c.FlashBonus, c.StartsAt, c.EndsAt).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating competition: %v", err)
+	}
+
+	problemIDs := make([]uuid.UUID, len(c.Problems))
+	for i, p := range c.Problems {
+		problemIDs[i] = p.ProblemID
+	}
+
+	// Anyone can see a competition, so its problems must be on public boards
+	err = checkAddableProblems(tx, problemIDs, c.OwnerID, true)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO competition_problems (competition_id, position, problem_id, points)
+		VALUES ($1, $2, $3, $4)
+	`)
+	if err != nil {
+		return fmt.Errorf("error preparing statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for i, p := range c.Problems {
+		_, err = stmt.Exec(c.ID, i, p.ProblemID, p.Points)
+		if err != nil {
+			return fmt.Errorf("error adding competition problem: %v", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetCompetition(competitionID uuid.UUID) (*Competition, error) {
+	var c Competition
+
+	err := d.QueryRow(`
+		SELECT id, name, owner_id, format, flash_bonus, starts_at, ends_at, created_at
+		FROM competitions
+		WHERE id = $1
+	`, competitionID).Scan(&c.ID, &c.Name, &c.OwnerID, &c.Format, &c.FlashBonus, &c.StartsAt, &c.EndsAt, &c.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrCompetitionNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error querying competition: %v", err)
+	}
+
+	rows, err := d.Query(`
+		SELECT problem_id, points
+		FROM competition_problems
+		WHERE competition_id = $1
+		ORDER BY position
+	`, competitionID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying competition problems: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p CompetitionProblem
+
+		err := rows.Scan(&p.ProblemID, &p.Points)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning competition problem: %v", err)
+		}
+
+		c.Problems = append(c.Problems, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating competition problems: %v", err)
+	}
+
+	return &c, nil
+}
+
+// GetCompetitions returns every competition, the latest to start first.
+// Problems are left out.
+func (d *DB) GetCompetitions() ([]Competition, error) {
+	rows, err := d.Query(`
+		SELECT id, name, owner_id, format, flash_bonus, starts_at, ends_at, created_at
+		FROM competitions
+		ORDER BY starts_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying competitions: %v", err)
+	}
+	defer rows.Close()
+
+	var competitions []Competition
+
+	for rows.Next() {
+		var c Competition
+
+		err := rows.Scan(&c.ID, &c.Name, &c.OwnerID, &c.Format, &c.FlashBonus, &c.StartsAt, &c.EndsAt, &c.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning competition: %v", err)
+		}
+
+		competitions = append(competitions, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating competitions: %v", err)
+	}
+
+	return competitions, nil
+}
+
+// RegisterCompetitor enters a user into a competition that hasn't ended.
+// Registering again does nothing.
+func (d *DB) RegisterCompetitor(competitionID, userID uuid.UUID) error {
+	res, err := d.Exec(`
+		INSERT INTO competition_competitors (competition_id, user_id)
+		SELECT id, $2::uuid
+		FROM competitions
+		WHERE id = $1 AND ends_at > NOW()
+		ON CONFLICT DO NOTHING
+	`, competitionID, userID)
+	if err != nil {
+		return fmt.Errorf("error registering competitor: %v", err)
+	}
+
+	added, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error registering competitor: %v", err)
+	}
+
+	if added == 0 {
+		var ended bool
+
+		err = d.QueryRow(`SELECT ends_at <= NOW() FROM competitions WHERE id = $1`, competitionID).Scan(&ended)
+
+		if err == sql.ErrNoRows {
+			return ErrCompetitionNotFound
+		}
+
+		if err != nil {
+			return fmt.Errorf("error querying competition: %v", err)
+		}
+
+		if ended {
+			return ErrCompetitionEnded
+		}
+	}
+
+	return nil
+}
+
+func (d *DB) WithdrawCompetitor(competitionID, userID uuid.UUID) error {
+	_, err := d.Exec(`
+		DELETE FROM competition_competitors
+		WHERE competition_id = $1 AND user_id = $2
+	`, competitionID, userID)
+	if err != nil {
+		return fmt.Errorf("error withdrawing competitor: %v", err)
+	}
+
+	return nil
+}
+
+// GetCompetitionEntries returns a competition's competitors in the order they
+// registered, and the attempts they made on its problems during it, oldest
+// first.
+func (d *DB) GetCompetitionEntries(competitionID uuid.UUID) ([]uuid.UUID, []CompetitionAttempt, error) {
+	rows, err := d.Query(`
+		SELECT user_id
+		FROM competition_competitors
+		WHERE competition_id = $1
+		ORDER BY registered_at, user_id
+	`, competitionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error querying competitors: %v", err)
+	}
+	defer rows.Close()
+
+	var competitors []uuid.UUID
+
+	for rows.Next() {
+		var id uuid.UUID
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning competitor: %v", err)
+		}
+
+		competitors = append(competitors, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating competitors: %v", err)
+	}
+
+	attemptRows, err := d.Query(`
+		SELECT a.user_id, a.problem_id, a.status, a.zone, a.attempted_at
+		FROM competitions c
+		JOIN competition_problems cp ON cp.competition_id = c.id
+		JOIN competition_competitors cc ON cc.competition_id = c.id
+		JOIN attempts a ON a.problem_id = cp.problem_id AND a.user_id = cc.user_id
+		WHERE c.id = $1
+			AND a.attempted_at >= c.starts_at
+			AND a.attempted_at < c.ends_at
+			AND NOT a.mirrored
+		ORDER BY a.attempted_at, a.id
+	`, competitionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error querying competition attempts: %v", err)
+	}
+	defer attemptRows.Close()
+
+	var attempts []CompetitionAttempt
+
+	for attemptRows.Next() {
+		var a CompetitionAttempt
+
+		err := attemptRows.Scan(&a.UserID, &a.ProblemID, &a.Status, &a.Zone, &a.AttemptedAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning competition attempt: %v", err)
+		}
+
+		attempts = append(attempts, a)
+	}
+
+	if err = attemptRows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating competition attempts: %v", err)
+	}
+
+	return competitors, attempts, nil
+}
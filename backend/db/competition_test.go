@@ -0,0 +1,186 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func competitionAttempt(userID, problemID uuid.UUID, status string, zone bool) CompetitionAttempt {
+	return CompetitionAttempt{UserID: userID, ProblemID: problemID, Status: status, Zone: zone}
+}
+
+func TestScoreCompetition(t *testing.T) {
+	var (
+		userA    = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+		userB    = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+		userC    = uuid.MustParse("00000000-0000-0000-0000-00000000000c")
+		problem1 = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+		problem2 = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	)
+
+	type standing struct {
+		userID uuid.UUID
+		rank   int
+		tops   int
+		zones  int
+		points int
+	}
+
+	twoProblems := []CompetitionProblem{{ProblemID: problem1, Points: 100}, {ProblemID: problem2, Points: 200}}
+
+	tests := []struct {
+		name        string
+		competition Competition
+		competitors []uuid.UUID
+		attempts    []CompetitionAttempt
+		want        []standing
+	}{
+		{
+			name:        "ifsc ranks tops above zones",
+			competition: Competition{Format: CompetitionFormatIFSC, Problems: twoProblems},
+			competitors: []uuid.UUID{userA, userB},
+			attempts: []CompetitionAttempt{
+				competitionAttempt(userB, problem1, AttemptStatusFailed, true),
+				competitionAttempt(userB, problem2, AttemptStatusFailed, true),
+				competitionAttempt(userA, problem1, AttemptStatusFailed, false),
+				competitionAttempt(userA, problem1, AttemptStatusSent, false),
+			},
+			want: []standing{
+				{userID: userA, rank: 1, tops: 1, zones: 1, points: 100},
+				{userID: userB, rank: 2, tops: 0, zones: 2, points: 0},
+			},
+		},
+		{
+			name:        "ifsc breaks equal tops on zones",
+			competition: Competition{Format: CompetitionFormatIFSC, Problems: twoProblems},
+			competitors: []uuid.UUID{userA, userB},
+			attempts: []CompetitionAttempt{
+				competitionAttempt(userA, problem1, AttemptStatusSent, false),
+				competitionAttempt(userB, problem1, AttemptStatusSent, false),
+				competitionAttempt(userB, problem2, AttemptStatusFailed, true),
+			},
+			want: []standing{
+				{userID: userB, rank: 1, tops: 1, zones: 2, points: 100},
+				{userID: userA, rank: 2, tops: 1, zones: 1, points: 100},
+			},
+		},
+		{
+			name:        "ifsc breaks equal tops and zones on top attempts",
+			competition: Competition{Format: CompetitionFormatIFSC, Problems: twoProblems},
+			competitors: []uuid.UUID{userA, userB},
+			attempts: []CompetitionAttempt{
+				competitionAttempt(userA, problem1, AttemptStatusFailed, true),
+				competitionAttempt(userA, problem1, AttemptStatusSent, false),
+				competitionAttempt(userB, problem1, AttemptStatusSent, false),
+			},
+			want: []standing{
+				{userID: userB, rank: 1, tops: 1, zones: 1, points: 100},
+				{userID: userA, rank: 2, tops: 1, zones: 1, points: 100},
+			},
+		},
+		{
+			name:        "ifsc breaks equal top attempts on zone attempts",
+			competition: Competition{Format: CompetitionFormatIFSC, Problems: twoProblems},
+			competitors: []uuid.UUID{userA, userB},
+			attempts: []CompetitionAttempt{
+				competitionAttempt(userA, problem1, AttemptStatusFailed, false),
+				competitionAttempt(userA, problem1, AttemptStatusFailed, true),
+				competitionAttempt(userA, problem1, AttemptStatusSent, false),
+				competitionAttempt(userB, problem1, AttemptStatusFailed, true),
+				competitionAttempt(userB, problem1, AttemptStatusFailed, false),
+				competitionAttempt(userB, problem1, AttemptStatusSent, false),
+			},
+			want: []standing{
+				{userID: userB, rank: 1, tops: 1, zones: 1, points: 100},
+				{userID: userA, rank: 2, tops: 1, zones: 1, points: 100},
+			},
+		},
+		{
+			name:        "top counts as zone",
+			competition: Competition{Format: CompetitionFormatIFSC, Problems: twoProblems},
+			competitors: []uuid.UUID{userA},
+			attempts: []CompetitionAttempt{
+				competitionAttempt(userA, problem1, AttemptStatusSent, false),
+				competitionAttempt(userA, problem2, AttemptStatusFailed, false),
+				competitionAttempt(userA, problem2, AttemptStatusSent, false),
+			},
+			want: []standing{
+				{userID: userA, rank: 1, tops: 2, zones: 2, points: 300},
+			},
+		},
+		{
+			name:        "flash bonus only for first attempt tops",
+			competition: Competition{Format: CompetitionFormatFlashBonus, FlashBonus: 50, Problems: twoProblems},
+			competitors: []uuid.UUID{userA, userB},
+			attempts: []CompetitionAttempt{
+				competitionAttempt(userA, problem1, AttemptStatusSent, false),
+				competitionAttempt(userB, problem1, AttemptStatusFailed, false),
+				competitionAttempt(userB, problem1, AttemptStatusSent, false),
+			},
+			want: []standing{
+				{userID: userA, rank: 1, tops: 1, zones: 1, points: 150},
+				{userID: userB, rank: 2, tops: 1, zones: 1, points: 100},
+			},
+		},
+		{
+			name:        "points ignore the flash bonus",
+			competition: Competition{Format: CompetitionFormatPoints, FlashBonus: 50, Problems: twoProblems},
+			competitors: []uuid.UUID{userA, userB},
+			attempts: []CompetitionAttempt{
+				competitionAttempt(userA, problem1, AttemptStatusSent, false),
+				competitionAttempt(userB, problem2, AttemptStatusFailed, false),
+				competitionAttempt(userB, problem2, AttemptStatusSent, false),
+			},
+			want: []standing{
+				{userID: userB, rank: 1, tops: 1, zones: 1, points: 200},
+				{userID: userA, rank: 2, tops: 1, zones: 1, points: 100},
+			},
+		},
+		{
+			name:        "equal scores share a rank",
+			competition: Competition{Format: CompetitionFormatIFSC, Problems: twoProblems},
+			competitors: []uuid.UUID{userA, userB, userC},
+			attempts: []CompetitionAttempt{
+				competitionAttempt(userA, problem1, AttemptStatusSent, false),
+				competitionAttempt(userB, problem1, AttemptStatusSent, false),
+			},
+			want: []standing{
+				{userID: userA, rank: 1, tops: 1, zones: 1, points: 100},
+				{userID: userB, rank: 1, tops: 1, zones: 1, points: 100},
+				{userID: userC, rank: 3, tops: 0, zones: 0, points: 0},
+			},
+		},
+		{
+			name:        "attempts by others or on other problems are ignored",
+			competition: Competition{Format: CompetitionFormatIFSC, Problems: twoProblems[:1]},
+			competitors: []uuid.UUID{userA},
+			attempts: []CompetitionAttempt{
+				competitionAttempt(userB, problem1, AttemptStatusSent, false),
+				competitionAttempt(userA, problem2, AttemptStatusSent, false),
+			},
+			want: []standing{
+				{userID: userA, rank: 1, tops: 0, zones: 0, points: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := ScoreCompetition(tt.competition, tt.competitors, tt.attempts)
+
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				r := results[i]
+				got := standing{userID: r.UserID, rank: r.Rank, tops: r.Tops, zones: r.Zones, points: r.Points}
+
+				if got != want {
+					t.Errorf("result %d: got %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
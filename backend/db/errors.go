@@ -3,6 +3,48 @@ package db
 import "errors"
 
 var (
-	ErrBoardNotFound   = errors.New("board not found")
-	ErrProblemNotFound = errors.New("problem not found")
+	ErrBoardNotFound             = errors.New("board not found")
+	ErrProblemNotFound           = errors.New("problem not found")
+	ErrProblemNotEditable        = errors.New("only draft problems can be edited")
+	ErrInvalidTransition         = errors.New("problem cannot make this transition from its current status")
+	ErrNotProblemSetter          = errors.New("only the problem's setter can do this")
+	ErrSelfReview                = errors.New("setters cannot review their own problems")
+	ErrRevisionNotFound          = errors.New("revision not found")
+	ErrNoMirror                  = errors.New("hold has no mirror")
+	ErrCannotGenerate            = errors.New("no problem could be generated from the board's holds")
+	ErrNoGradeModel              = errors.New("no grade model has been trained")
+	ErrBetaNotFound              = errors.New("beta not found")
+	ErrDuplicateBeta             = errors.New("user already has a beta for this problem")
+	ErrNotBetaAuthor             = errors.New("beta belongs to another user")
+	ErrCircuitNotFound           = errors.New("circuit not found")
+	ErrNotCircuitOwner           = errors.New("circuit belongs to another user")
+	ErrUnpublishedProblem        = errors.New("only published problems can be added")
+	ErrPrivateProblem            = errors.New("only problems on boards visible to everyone it is shared with can be added")
+	ErrAlreadySent               = errors.New("problem has already been sent")
+	ErrSessionNotFound           = errors.New("session not found")
+	ErrSessionOpen               = errors.New("user already has an open session")
+	ErrSessionEnded              = errors.New("session has already ended")
+	ErrNotSessionOwner           = errors.New("session belongs to another user")
+	ErrCompetitionNotFound       = errors.New("competition not found")
+	ErrCompetitionEnded          = errors.New("competition has ended")
+	ErrWebhookNotFound           = errors.New("webhook not found")
+	ErrCommentNotFound           = errors.New("comment not found")
+	ErrParentCommentNotFound     = errors.New("parent comment not found")
+	ErrCommentDeleted            = errors.New("comment has been deleted")
+	ErrNotCommentAuthor          = errors.New("comment belongs to another user")
+	ErrNotCommentModerator       = errors.New("only the author, the problem's setter or the board owner can delete a comment")
+	ErrReportNotFound            = errors.New("report not found")
+	ErrReportTargetNotFound      = errors.New("reported content not found")
+	ErrAlreadyReported           = errors.New("user already has an open report on this content")
+	ErrReportResolved            = errors.New("report has already been resolved")
+	ErrNotModerator              = errors.New("user cannot moderate this content")
+	ErrSelfFollow                = errors.New("users cannot follow themselves")
+	ErrInvalidCursor             = errors.New("invalid cursor")
+	ErrOrganizationNotFound      = errors.New("organization not found")
+	ErrNotOrganizationMember     = errors.New("user is not a member of this organization")
+	ErrNotOrganizationOwner      = errors.New("only the organization's owners can do this")
+	ErrLastOrganizationOwner     = errors.New("an organization must keep at least one owner")
+	ErrAlreadyOrganizationMember = errors.New("user is already a member of this organization")
+	ErrAlreadyInvited            = errors.New("user already has an invite to this organization")
+	ErrInviteNotFound            = errors.New("invite not found")
 )
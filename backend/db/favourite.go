@@ -0,0 +1,79 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Favourite is a problem a user has bookmarked.
+type Favourite struct {
+	Problem      Problem   `json:"problem"`
+	FavouritedAt time.Time `json:"favourited_at"`
+}
+
+// AddFavourite bookmarks a problem for a user. Bookmarking it again does
+// nothing.
+func (d *DB) AddFavourite(userID, problemID uuid.UUID) error {
+	_, err := d.Exec(`
+		INSERT INTO favourites (user_id, problem_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, userID, problemID)
+	if err != nil {
+		return fmt.Errorf("error adding favourite: %v", err)
+	}
+
+	return nil
+}
+
+func (d *DB) RemoveFavourite(userID, problemID uuid.UUID) error {
+	_, err := d.Exec(`DELETE FROM favourites WHERE user_id = $1 AND problem_id = $2`, userID, problemID)
+	if err != nil {
+		return fmt.Errorf("error removing favourite: %v", err)
+	}
+
+	return nil
+}
+
+// GetFavourites returns a user's bookmarked problems, most recent first.
+// Problems on boards they can no longer see, or that a moderator has since
+// hidden, are left out.
+func (d *DB) GetFavourites(userID uuid.UUID) ([]Favourite, error) {
+	rows, err := d.Query(`
+		SELECT `+problemColumns+`, f.favourited_at
+		FROM problems
+		JOIN (
+			SELECT problem_id, created_at AS favourited_at
+			FROM favourites
+			WHERE user_id = $1
+		) f ON f.problem_id = problems.id
+		WHERE board_visible_to(problems.board_id, $1)
+			AND (problems.hidden_at IS NULL OR EXISTS (SELECT 1 FROM moderators WHERE user_id = $1))
+		ORDER BY f.favourited_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying favourites: %v", err)
+	}
+	defer rows.Close()
+
+	var favourites []Favourite
+
+	for rows.Next() {
+		var f Favourite
+
+		err := scanProblem(rows, &f.Problem, &f.FavouritedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning favourite: %v", err)
+		}
+
+		favourites = append(favourites, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating favourites: %v", err)
+	}
+
+	return favourites, nil
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vizvim/bloc/backend/events"
+)
+
+// Follow is one user following another.
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// FollowUser makes follower follow followee. Following someone again does
+// nothing.
+func (d *DB) FollowUser(followerID, followeeID uuid.UUID) error {
+	_, err := d.Exec(`
+		INSERT INTO follows (follower_id, followee_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, followerID, followeeID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "check_not_self_follow" {
+			return ErrSelfFollow
+		}
+
+		return fmt.Errorf("error following user: %v", err)
+	}
+
+	return nil
+}
+
+func (d *DB) UnfollowUser(followerID, followeeID uuid.UUID) error {
+	_, err := d.Exec(`DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2`, followerID, followeeID)
+	if err != nil {
+		return fmt.Errorf("error unfollowing user: %v", err)
+	}
+
+	return nil
+}
+
+// GetFollowing returns the users someone follows, most recently followed
+// first.
+func (d *DB) GetFollowing(userID uuid.UUID) ([]Follow, error) {
+	return d.getFollows(`
+		SELECT followee_id, created_at
+		FROM follows
+		WHERE follower_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+}
+
+// GetFollowers returns the users following someone, most recent first.
+func (d *DB) GetFollowers(userID uuid.UUID) ([]Follow, error) {
+	return d.getFollows(`
+		SELECT follower_id, created_at
+		FROM follows
+		WHERE followee_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+}
+
+func (d *DB) getFollows(query string, userID uuid.UUID) ([]Follow, error) {
+	rows, err := d.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying follows: %v", err)
+	}
+	defer rows.Close()
+
+	follows := []Follow{}
+
+	for rows.Next() {
+		var f Follow
+
+		err := rows.Scan(&f.UserID, &f.FollowedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning follow: %v", err)
+		}
+
+		follows = append(follows, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating follows: %v", err)
+	}
+
+	return follows, nil
+}
+
+// FeedItem is something a followed user did: published a problem they set,
+// or sent a problem. UserID is the setter or the climber.
+type FeedItem struct {
+	ID      uuid.UUID   `json:"id"`
+	Type    events.Type `json:"type"`
+	UserID  uuid.UUID   `json:"user_id"`
+	Problem Problem     `json:"problem"`
+	At      time.Time   `json:"at"`
+}
+
+// FeedCursor marks where a page of the feed ended. The next page starts with
+// the item after it.
+type FeedCursor struct {
+	At time.Time
+	ID uuid.UUID
+}
+
+func (c FeedCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.At.Format(time.RFC3339Nano) + "," + c.ID.String()))
+}
+
+// ParseFeedCursor reads a cursor made by FeedCursor.String.
+func ParseFeedCursor(s string) (*FeedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	at, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	var c FeedCursor
+
+	c.At, err = time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c.ID, err = uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// GetFeed returns up to limit of the newest items from the users someone
+// follows, starting after cursor when it isn't nil. The returned cursor is
+// nil on the last page. Problems that have since been hidden or unpublished,
+// or are on boards the user can't see, are left out.
+func (d *DB) GetFeed(userID uuid.UUID, cursor *FeedCursor, limit int) ([]FeedItem, *FeedCursor, error) {
+	var (
+		at *time.Time
+		id *uuid.UUID
+	)
+
+	if cursor != nil {
+		at, id = &cursor.At, &cursor.ID
+	}
+
+	rows, err := d.Query(`
+		WITH followed AS (
+			SELECT followee_id FROM follows WHERE follower_id = $1
+		), items AS (
+			SELECT t.id AS item_id, $2::text AS item_type, p.setter_id AS actor_id, t.problem_id AS item_problem_id, t.created_at AS item_at
+			FROM problem_transitions t
+			JOIN problems p ON p.id = t.problem_id
+			WHERE t.action = 'approve' AND p.setter_id IN (SELECT followee_id FROM followed)
+			UNION ALL
+			SELECT a.id, $3::text, a.user_id, a.problem_id, a.attempted_at
+			FROM attempts a
+			WHERE a.status = 'sent' AND a.user_id IN (SELECT followee_id FROM followed)
+		)
+		SELECT `+problemColumns+`, i.item_id, i.item_type, i.actor_id, i.item_at
+		FROM items i
+		JOIN problems ON problems.id = i.item_problem_id
+		WHERE problems.status = 'PUBLISHED'
+			AND problems.hidden_at IS NULL
+			AND board_visible_to(problems.board_id, $1)
+			AND ($4::timestamp IS NULL OR (i.item_at, i.item_id) < ($4::timestamp, $5::uuid))
+		ORDER BY i.item_at DESC, i.item_id DESC
+		LIMIT $6
+	`, userID, events.ProblemPublished, events.ProblemSent, at, id, limit+1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error querying feed: %v", err)
+	}
+	defer rows.Close()
+
+	items := []FeedItem{}
+
+	for rows.Next() {
+		var item FeedItem
+
+		err := scanProblem(rows, &item.Problem, &item.ID, &item.Type, &item.UserID, &item.At)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning feed item: %v", err)
+		}
+
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating feed: %v", err)
+	}
+
+	if len(items) <= limit {
+		return items, nil, nil
+	}
+
+	items = items[:limit]
+	last := items[limit-1]
+
+	return items, &FeedCursor{At: last.At, ID: last.ID}, nil
+}
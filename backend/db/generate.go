@@ -0,0 +1,140 @@
+package db
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// GenerateOptions tune GenerateProblemHolds. Coordinates are the same
+// normalised image coordinates as hold vertices, so y grows down the board.
+type GenerateOptions struct {
+	// Reach is the furthest distance between consecutive hand holds.
+	Reach float64
+	// StartBand is how far up from the bottom of the board start holds can be.
+	StartBand float64
+	// FinishBand is how far down from the top of the board finish holds can be.
+	FinishBand float64
+	// FootHolds is the most foot holds to add below the start.
+	FootHolds int
+}
+
+// DefaultGenerateOptions returns options that suit a board of roughly standard
+// proportions, with moves getting bigger as the grade goes up.
+func DefaultGenerateOptions(grade int) GenerateOptions {
+	return GenerateOptions{
+		Reach:      0.15 + 0.015*float64(grade),
+		StartBand:  0.4,
+		FinishBand: 0.2,
+		FootHolds:  2,
+	}
+}
+
+// GenerateProblemHolds builds a random problem from a board's holds: one or two
+// start holds low on the board, hand holds climbing upwards no further than
+// opts.Reach apart, a finish hold near the top and a few foot holds below the
+// start. It returns ErrCannotGenerate if the board has no such route.
+func GenerateProblemHolds(holds []Hold, opts GenerateOptions, rng *rand.Rand) ([]ProblemHold, error) {
+	centroids := make(map[uuid.UUID]Point, len(holds))
+	for _, h := range holds {
+		centroids[h.ID] = h.Centroid()
+	}
+
+	distance := func(a, b uuid.UUID) float64 {
+		return math.Hypot(centroids[a].X-centroids[b].X, centroids[a].Y-centroids[b].Y)
+	}
+
+	var starts []uuid.UUID
+
+	for _, h := range holds {
+		if centroids[h.ID].Y >= 1-opts.StartBand {
+			starts = append(starts, h.ID)
+		}
+	}
+
+	if len(starts) == 0 {
+		return nil, ErrCannotGenerate
+	}
+
+	used := make(map[uuid.UUID]bool)
+	result := make([]ProblemHold, 0, 8)
+
+	add := func(id uuid.UUID, t HoldType) {
+		used[id] = true
+		result = append(result, ProblemHold{HoldID: id, Type: t})
+	}
+
+	start := starts[rng.IntN(len(starts))]
+	add(start, HoldTypeStart)
+
+	// Sometimes match the start with a second hold at about the same height
+	if rng.IntN(2) == 0 {
+		var partners []uuid.UUID
+
+		for _, id := range starts {
+			if !used[id] && distance(start, id) <= opts.Reach/2 && math.Abs(centroids[id].Y-centroids[start].Y) < opts.Reach/4 {
+				partners = append(partners, id)
+			}
+		}
+
+		if len(partners) > 0 {
+			add(partners[rng.IntN(len(partners))], HoldTypeStart)
+		}
+	}
+
+	current := start
+
+	for centroids[current].Y > opts.FinishBand {
+		var finishes, next []uuid.UUID
+
+		for _, h := range holds {
+			if used[h.ID] || distance(current, h.ID) > opts.Reach {
+				continue
+			}
+
+			rise := centroids[current].Y - centroids[h.ID].Y
+
+			switch {
+			case centroids[h.ID].Y <= opts.FinishBand:
+				finishes = append(finishes, h.ID)
+			case rise >= opts.Reach/3:
+				next = append(next, h.ID)
+			}
+		}
+
+		if len(finishes) > 0 {
+			add(finishes[rng.IntN(len(finishes))], HoldTypeFinish)
+			break
+		}
+
+		if len(next) == 0 {
+			return nil, ErrCannotGenerate
+		}
+
+		current = next[rng.IntN(len(next))]
+		add(current, HoldTypeHand)
+	}
+
+	// A start hold already near the top is not much of a problem
+	if result[len(result)-1].Type != HoldTypeFinish {
+		return nil, ErrCannotGenerate
+	}
+
+	var feet []uuid.UUID
+
+	for _, h := range holds {
+		if !used[h.ID] && centroids[h.ID].Y > centroids[start].Y && distance(start, h.ID) <= opts.Reach {
+			feet = append(feet, h.ID)
+		}
+	}
+
+	sort.Slice(feet, func(i, j int) bool { return distance(start, feet[i]) < distance(start, feet[j]) })
+
+	for i := 0; i < len(feet) && i < opts.FootHolds; i++ {
+		add(feet[i], HoldTypeFoot)
+	}
+
+	return result, nil
+}
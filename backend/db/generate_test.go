@@ -0,0 +1,150 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGenerateProblemHolds(t *testing.T) {
+	// hold is a small square hold centred on (x, y), numbered so failures are
+	// easy to read
+	hold := func(n int, x, y float64) Hold {
+		const r = 0.005
+
+		id := uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", n))
+
+		return Hold{ID: id, Vertices: []Point{{x - r, y - r}, {x + r, y - r}, {x + r, y + r}, {x - r, y + r}}}
+	}
+
+	ladder := []Hold{
+		hold(1, 0.5, 0.9),
+		hold(2, 0.55, 0.88),
+		hold(3, 0.5, 0.75),
+		hold(4, 0.45, 0.6),
+		hold(5, 0.5, 0.45),
+		hold(6, 0.55, 0.3),
+		hold(7, 0.5, 0.15),
+		hold(8, 0.48, 0.97),
+		hold(9, 0.52, 0.98),
+	}
+
+	opts := GenerateOptions{Reach: 0.2, StartBand: 0.15, FinishBand: 0.2, FootHolds: 1}
+
+	tests := []struct {
+		name    string
+		holds   []Hold
+		opts    GenerateOptions
+		wantErr error
+	}{
+		{
+			name:  "climbs the ladder",
+			holds: ladder,
+			opts:  opts,
+		},
+		{
+			name:    "no holds in the start band",
+			holds:   ladder[2:7],
+			opts:    opts,
+			wantErr: ErrCannotGenerate,
+		},
+		{
+			name:    "gap bigger than the reach",
+			holds:   append(append([]Hold{}, ladder[:3]...), ladder[5:]...),
+			opts:    opts,
+			wantErr: ErrCannotGenerate,
+		},
+		{
+			name:    "start already in the finish band",
+			holds:   []Hold{hold(1, 0.5, 0.1)},
+			opts:    GenerateOptions{Reach: 0.2, StartBand: 1, FinishBand: 0.2},
+			wantErr: ErrCannotGenerate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			centroids := make(map[uuid.UUID]Point, len(tt.holds))
+			for _, h := range tt.holds {
+				centroids[h.ID] = h.Centroid()
+			}
+
+			for seed := range uint64(20) {
+				holds, err := GenerateProblemHolds(tt.holds, tt.opts, rand.New(rand.NewPCG(seed, seed)))
+
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("seed %d: got error %v, want %v", seed, err, tt.wantErr)
+					}
+
+					continue
+				}
+
+				if err != nil {
+					t.Fatalf("seed %d: unexpected error: %v", seed, err)
+				}
+
+				checkGeneratedProblem(t, seed, holds, centroids, tt.opts)
+			}
+		})
+	}
+}
+
+// checkGeneratedProblem checks a generated problem starts low, climbs within
+// reach to a finish near the top and only uses each hold once.
+func checkGeneratedProblem(t *testing.T, seed uint64, holds []ProblemHold, centroids map[uuid.UUID]Point, opts GenerateOptions) {
+	t.Helper()
+
+	seen := make(map[uuid.UUID]bool, len(holds))
+	counts := make(map[HoldType]int)
+
+	var previous *Point
+
+	for i, h := range holds {
+		if seen[h.HoldID] {
+			t.Errorf("seed %d: hold %s used more than once", seed, h.HoldID)
+		}
+
+		seen[h.HoldID] = true
+		counts[h.Type]++
+		c := centroids[h.HoldID]
+
+		switch h.Type {
+		case HoldTypeStart:
+			if i > 1 {
+				t.Errorf("seed %d: start hold at position %d", seed, i)
+			}
+
+			if c.Y < 1-opts.StartBand {
+				t.Errorf("seed %d: start hold at y=%f is above the start band", seed, c.Y)
+			}
+		case HoldTypeFinish:
+			if c.Y > opts.FinishBand {
+				t.Errorf("seed %d: finish hold at y=%f is below the finish band", seed, c.Y)
+			}
+		}
+
+		// Moves are measured from the first start hold
+		if h.Type == HoldTypeFoot || (h.Type == HoldTypeStart && previous != nil) {
+			continue
+		}
+
+		if previous != nil && math.Hypot(c.X-previous.X, c.Y-previous.Y) > opts.Reach {
+			t.Errorf("seed %d: move to hold %s is out of reach", seed, h.HoldID)
+		}
+
+		previous = &c
+	}
+
+	if counts[HoldTypeFinish] != 1 {
+		t.Errorf("seed %d: got %d finish holds, want 1", seed, counts[HoldTypeFinish])
+	}
+
+	if counts[HoldTypeFoot] > opts.FootHolds {
+		t.Errorf("seed %d: got %d foot holds, want at most %d", seed, counts[HoldTypeFoot], opts.FootHolds)
+	}
+}
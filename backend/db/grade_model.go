@@ -0,0 +1,139 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GradeModel is a trained linear model over problem features. Features are
+// standardised with Means and Scales before Weights are applied.
+type GradeModel struct {
+	ID        uuid.UUID `json:"id"`
+	Features  []string  `json:"features"`
+	Means     []float64 `json:"means"`
+	Scales    []float64 `json:"scales"`
+	Weights   []float64 `json:"weights"`
+	Intercept float64   `json:"intercept"`
+	Samples   int       `json:"samples"`
+	RMSE      float64   `json:"rmse"`
+	TrainedAt time.Time `json:"trained_at"`
+}
+
+// GradeSample is a graded problem that has been sent, along with its holds,
+// for training a GradeModel.
+type GradeSample struct {
+	Problem Problem
+	Holds   []ProblemHold
+}
+
+type gradeModelParams struct {
+	Features  []string  `json:"features"`
+	Means     []float64 `json:"means"`
+	Scales    []float64 `json:"scales"`
+	Weights   []float64 `json:"weights"`
+	Intercept float64   `json:"intercept"`
+}
+
+func (d *DB) SaveGradeModel(m *GradeModel) error {
+	params, err := json.Marshal(gradeModelParams{
+		Features:  m.Features,
+		Means:     m.Means,
+		Scales:    m.Scales,
+		Weights:   m.Weights,
+		Intercept: m.Intercept,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshaling grade model: %v", err)
+	}
+
+	err = d.QueryRow(`
+		INSERT INTO grade_models (model, samples, rmse)
+		VALUES ($1, $2, $3)
+		RETURNING id, trained_at
+	`, params, m.Samples, m.RMSE).Scan(&m.ID, &m.TrainedAt)
+	if err != nil {
+		return fmt.Errorf("error saving grade model: %v", err)
+	}
+
+	return nil
+}
+
+// GetLatestGradeModel returns the most recently trained model.
+func (d *DB) GetLatestGradeModel() (*GradeModel, error) {
+	var (
+		m      GradeModel
+		params []byte
+	)
+
+	err := d.QueryRow(`
+		SELECT id, model, samples, rmse, trained_at
+		FROM grade_models
+		ORDER BY trained_at DESC
+		LIMIT 1
+	`).Scan(&m.ID, &params, &m.Samples, &m.RMSE, &m.TrainedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNoGradeModel
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error querying grade model: %v", err)
+	}
+
+	var p gradeModelParams
+
+	err = json.Unmarshal(params, &p)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling grade model: %v", err)
+	}
+
+	m.Features, m.Means, m.Scales, m.Weights, m.Intercept = p.Features, p.Means, p.Scales, p.Weights, p.Intercept
+
+	return &m, nil
+}
+
+// GetGradeTrainingSet returns every graded, published or archived problem
+// that someone has sent, so its grade has been tested by climbing it.
+func (d *DB) GetGradeTrainingSet() ([]GradeSample, error) {
+	rows, err := d.Query(`
+		SELECT ` + problemColumns + `
+		FROM problems p
+		WHERE grade IS NOT NULL
+			AND status IN ('PUBLISHED', 'ARCHIVED')
+			AND EXISTS (SELECT 1 FROM attempts a WHERE a.problem_id = p.id AND a.status = 'sent')
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying graded problems: %v", err)
+	}
+	defer rows.Close()
+
+	var samples []GradeSample
+
+	for rows.Next() {
+		var s GradeSample
+
+		err := scanProblem(rows, &s.Problem)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning problem: %v", err)
+		}
+
+		samples = append(samples, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graded problems: %v", err)
+	}
+
+	for i := range samples {
+		samples[i].Holds, err = d.GetProblemHolds(samples[i].Problem.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return samples, nil
+}
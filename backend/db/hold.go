@@ -3,9 +3,11 @@ package db
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vizvim/bloc/backend/events"
 	"github.com/vizvim/bloc/backend/validator"
 )
 
@@ -25,13 +27,13 @@ type Hold struct {
 func (h Hold) Validate() map[string]string {
 	v := validator.New()
 
-	v.Check(h.BoardID != uuid.Nil, "BoardID", "boardID must be provided")
-	v.Check(len(h.Vertices) >= 3, "Vertices", "hold must have at least 3 vertices")
+	v.Check(h.BoardID != uuid.Nil, "boardID", "must be provided")
+	v.Check(len(h.Vertices) >= 3, "vertices", "must contain at least 3 vertices")
 
 	// Validate all vertices coordinates
 	for i, vertex := range h.Vertices {
-		v.Check(vertex.X >= 0 && vertex.X <= 1, fmt.Sprintf("Vertices[%d].X", i), "vertex X must be between 0 and 1")
-		v.Check(vertex.Y >= 0 && vertex.Y <= 1, fmt.Sprintf("Vertices[%d].Y", i), "vertex Y must be between 0 and 1")
+		v.Check(vertex.X >= 0 && vertex.X <= 1, fmt.Sprintf("vertices[%d].x", i), "must be between 0 and 1")
+		v.Check(vertex.Y >= 0 && vertex.Y <= 1, fmt.Sprintf("vertices[%d].y", i), "must be between 0 and 1")
 	}
 
 	if v.Valid() {
@@ -41,6 +43,48 @@ func (h Hold) Validate() map[string]string {
 	return v.Errors
 }
 
+// Centroid returns the centre of mass of the hold's polygon, or the mean of
+// its vertices when the polygon has no area.
+func (h Hold) Centroid() Point {
+	var area, cx, cy float64
+
+	for i := range h.Vertices {
+		a := h.Vertices[i]
+		b := h.Vertices[(i+1)%len(h.Vertices)]
+		cross := a.X*b.Y - b.X*a.Y
+
+		area += cross
+		cx += (a.X + b.X) * cross
+		cy += (a.Y + b.Y) * cross
+	}
+
+	if math.Abs(area) < 1e-12 {
+		var mean Point
+
+		for _, p := range h.Vertices {
+			mean.X += p.X / float64(len(h.Vertices))
+			mean.Y += p.Y / float64(len(h.Vertices))
+		}
+
+		return mean
+	}
+
+	return Point{X: cx / (3 * area), Y: cy / (3 * area)}
+}
+
+// Area returns the area of the hold's polygon as a fraction of the board image.
+func (h Hold) Area() float64 {
+	var area float64
+
+	for i := range h.Vertices {
+		a := h.Vertices[i]
+		b := h.Vertices[(i+1)%len(h.Vertices)]
+		area += a.X*b.Y - b.X*a.Y
+	}
+
+	return math.Abs(area) / 2
+}
+
 func (d *DB) CreateHolds(boardID uuid.UUID, holds []*Hold) error {
 	var exists bool
 
@@ -100,6 +144,15 @@ func (d *DB) CreateHolds(boardID uuid.UUID, holds []*Hold) error {
 		}
 	}
 
+	err = enqueueWebhooks(tx, events.Event{Type: events.HoldLayoutChanged, BoardID: boardID, Data: holds}, nil)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("error rolling back transaction: %v: %v", rollbackErr, err)
+		}
+
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("error committing transaction: %v", err)
@@ -239,6 +292,15 @@ func (d *DB) UpdateHolds(boardID uuid.UUID, holds []*Hold) error {
 		}
 	}
 
+	err = enqueueWebhooks(tx, events.Event{Type: events.HoldLayoutChanged, BoardID: boardID, Data: holds}, nil)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("error rolling back transaction: %v: %v", rollbackErr, err)
+		}
+
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error committing transaction: %v", err)
 	}
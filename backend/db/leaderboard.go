@@ -0,0 +1,119 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LeaderboardPeriod string
+
+const (
+	LeaderboardPeriodWeek    LeaderboardPeriod = "week"
+	LeaderboardPeriodMonth   LeaderboardPeriod = "month"
+	LeaderboardPeriodSeason  LeaderboardPeriod = "season"
+	LeaderboardPeriodAllTime LeaderboardPeriod = "all-time"
+)
+
+// Leaderboard scoring. Every problem sent in the period scores once, worth
+// more the harder it is, with a bonus when it was sent on the climber's first
+// ever attempt. Ungraded problems score as V0.
+const (
+	LeaderboardSendPoints  = 100
+	LeaderboardGradePoints = 100
+	LeaderboardFlashBonus  = 50
+)
+
+// Start returns when the period containing now began, in UTC. Weeks start on
+// Monday and seasons are calendar quarters. All-time has no start.
+func (p LeaderboardPeriod) Start(now time.Time) *time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var start time.Time
+
+	switch p {
+	case LeaderboardPeriodWeek:
+		start = weekStart(now)
+	case LeaderboardPeriodMonth:
+		start = day.AddDate(0, 0, 1-day.Day())
+	case LeaderboardPeriodSeason:
+		start = time.Date(now.Year(), now.Month()-(now.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return nil
+	}
+
+	return &start
+}
+
+type LeaderboardEntry struct {
+	Rank        int       `json:"rank"`
+	UserID      uuid.UUID `json:"user_id"`
+	Score       int       `json:"score"`
+	Sends       int       `json:"sends"`
+	Flashes     int       `json:"flashes"`
+	HardestSend *int      `json:"hardest_send"`
+}
+
+// GetLeaderboard ranks climbers on a board by the problems they sent since
+// start, or ever when start is nil. Climbers with the same score share a rank.
+func (d *DB) GetLeaderboard(boardID uuid.UUID, start *time.Time, p Pagination) ([]LeaderboardEntry, Metadata, error) {
+	rows, err := d.Query(`
+		WITH sends AS (
+			SELECT DISTINCT ON (a.user_id, a.problem_id)
+				a.user_id,
+				p.grade,
+				NOT EXISTS (
+					SELECT 1 FROM attempts e
+					WHERE e.user_id = a.user_id AND e.problem_id = a.problem_id
+						AND (e.attempted_at, e.id) < (a.attempted_at, a.id)
+				) AS flashed
+			FROM attempts a
+			JOIN problems p ON p.id = a.problem_id
+			WHERE p.board_id = $1
+				AND a.status = 'sent'
+				AND ($2::timestamp IS NULL OR a.attempted_at >= $2)
+			ORDER BY a.user_id, a.problem_id, a.attempted_at, a.id
+		), scores AS (
+			SELECT
+				user_id,
+				SUM($3 + $4 * COALESCE(grade, 0) + CASE WHEN flashed THEN $5 ELSE 0 END) AS score,
+				COUNT(*) AS sends,
+				COUNT(*) FILTER (WHERE flashed) AS flashes,
+				MAX(grade) AS hardest_send
+			FROM sends
+			GROUP BY user_id
+		)
+		SELECT COUNT(*) OVER (), RANK() OVER (ORDER BY score DESC), user_id, score, sends, flashes, hardest_send
+		FROM scores
+		ORDER BY score DESC, hardest_send DESC NULLS LAST, user_id
+		LIMIT $6 OFFSET $7
+	`, boardID, start, LeaderboardSendPoints, LeaderboardGradePoints, LeaderboardFlashBonus, p.limit(), p.offset())
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("error querying leaderboard: %v", err)
+	}
+	defer rows.Close()
+
+	var (
+		totalRecords int
+		entries      = []LeaderboardEntry{}
+	)
+
+	for rows.Next() {
+		var e LeaderboardEntry
+
+		err := rows.Scan(&totalRecords, &e.Rank, &e.UserID, &e.Score, &e.Sends, &e.Flashes, &e.HardestSend)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf("error scanning leaderboard entry: %v", err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, fmt.Errorf("error iterating leaderboard: %v", err)
+	}
+
+	return entries, calculateMetadata(totalRecords, p), nil
+}
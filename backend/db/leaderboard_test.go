@@ -0,0 +1,52 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaderboardPeriodStart(t *testing.T) {
+	tests := []struct {
+		name   string
+		period LeaderboardPeriod
+		now    string
+		want   string
+	}{
+		{name: "week from midweek", period: LeaderboardPeriodWeek, now: "2024-05-15T13:00:00Z", want: "2024-05-13T00:00:00Z"},
+		{name: "week from monday", period: LeaderboardPeriodWeek, now: "2024-05-13T00:00:00Z", want: "2024-05-13T00:00:00Z"},
+		{name: "week from sunday", period: LeaderboardPeriodWeek, now: "2024-05-19T23:59:59Z", want: "2024-05-13T00:00:00Z"},
+		{name: "week in utc", period: LeaderboardPeriodWeek, now: "2024-05-13T01:00:00+02:00", want: "2024-05-06T00:00:00Z"},
+		{name: "month", period: LeaderboardPeriodMonth, now: "2024-02-29T18:00:00Z", want: "2024-02-01T00:00:00Z"},
+		{name: "first quarter", period: LeaderboardPeriodSeason, now: "2024-03-31T12:00:00Z", want: "2024-01-01T00:00:00Z"},
+		{name: "second quarter", period: LeaderboardPeriodSeason, now: "2024-04-01T00:00:00Z", want: "2024-04-01T00:00:00Z"},
+		{name: "last quarter", period: LeaderboardPeriodSeason, now: "2024-12-31T23:00:00Z", want: "2024-10-01T00:00:00Z"},
+		{name: "all time", period: LeaderboardPeriodAllTime, now: "2024-05-15T13:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := tt.period.Start(now)
+
+			if tt.want == "" {
+				if start != nil {
+					t.Errorf("got %s, want no start", start)
+				}
+
+				return
+			}
+
+			if start == nil {
+				t.Fatalf("got no start, want %s", tt.want)
+			}
+
+			if got := start.Format(time.RFC3339); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+// MirrorPair maps a hold to the hold in the same place on the other side of a
+// symmetric board. Holds on the centre line mirror themselves.
+type MirrorPair struct {
+	HoldID       uuid.UUID `json:"holdID"`
+	MirrorHoldID uuid.UUID `json:"mirrorHoldID"`
+}
+
+// ComputeMirrorPairs pairs up holds whose centroids land within tolerance of
+// each other when reflected across the vertical centre line of the board.
+// Closest candidates are paired first, and each hold is used at most once, so
+// the result is a consistent mapping. Holds without a partner are returned as
+// unmatched.
+func ComputeMirrorPairs(holds []Hold, tolerance float64) ([]MirrorPair, []uuid.UUID) {
+	type candidate struct {
+		a, b     int
+		distance float64
+	}
+
+	centroids := make([]Point, len(holds))
+	for i, h := range holds {
+		centroids[i] = h.Centroid()
+	}
+
+	var candidates []candidate
+
+	for i := range holds {
+		reflected := Point{X: 1 - centroids[i].X, Y: centroids[i].Y}
+
+		for j := i; j < len(holds); j++ {
+			distance := math.Hypot(reflected.X-centroids[j].X, reflected.Y-centroids[j].Y)
+			if distance <= tolerance {
+				candidates = append(candidates, candidate{a: i, b: j, distance: distance})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	matched := make([]bool, len(holds))
+
+	var pairs []MirrorPair
+
+	for _, c := range candidates {
+		if matched[c.a] || matched[c.b] {
+			continue
+		}
+
+		matched[c.a] = true
+		matched[c.b] = true
+
+		pairs = append(pairs, MirrorPair{HoldID: holds[c.a].ID, MirrorHoldID: holds[c.b].ID})
+		if c.a != c.b {
+			pairs = append(pairs, MirrorPair{HoldID: holds[c.b].ID, MirrorHoldID: holds[c.a].ID})
+		}
+	}
+
+	var unmatched []uuid.UUID
+
+	for i, h := range holds {
+		if !matched[i] {
+			unmatched = append(unmatched, h.ID)
+		}
+	}
+
+	return pairs, unmatched
+}
+
+// ValidateMirrorPairs checks that an explicit mapping only uses holds from the
+// board, maps each hold once, and agrees with itself in both directions.
+func ValidateMirrorPairs(pairs []MirrorPair, boardHolds []Hold) map[string]string {
+	v := validator.New()
+
+	v.Check(len(pairs) > 0, "pairs", "must contain at least one pair")
+
+	onBoard := make(map[uuid.UUID]bool, len(boardHolds))
+	for _, h := range boardHolds {
+		onBoard[h.ID] = true
+	}
+
+	mapping := make(map[uuid.UUID]uuid.UUID, len(pairs))
+
+	for i, p := range pairs {
+		key := fmt.Sprintf("pairs[%d]", i)
+
+		v.Check(onBoard[p.HoldID], key+".holdID", "must be a hold on the board")
+		v.Check(onBoard[p.MirrorHoldID], key+".mirrorHoldID", "must be a hold on the board")
+
+		if _, exists := mapping[p.HoldID]; exists {
+			v.AddError(key+".holdID", "must not be mapped more than once")
+		}
+
+		mapping[p.HoldID] = p.MirrorHoldID
+	}
+
+	for i, p := range pairs {
+		back, ok := mapping[p.MirrorHoldID]
+		v.Check(!ok || back == p.HoldID, fmt.Sprintf("pairs[%d].mirrorHoldID", i), "is mirrored to a different hold")
+	}
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// SetBoardMirror marks a board as symmetric and replaces its mirror mapping.
+// Pairs are stored in both directions.
+func (d *DB) SetBoardMirror(boardID uuid.UUID, pairs []MirrorPair) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	result, err := tx.Exec(`
+		UPDATE boards
+		SET symmetric = TRUE, updated_at = NOW(), version = version + 1
+		WHERE id = $1
+	`, boardID)
+	if err != nil {
+		return fmt.Errorf("error updating board: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking updated board: %v", err)
+	}
+
+	if rows == 0 {
+		return ErrBoardNotFound
+	}
+
+	_, err = tx.Exec(`DELETE FROM hold_mirrors WHERE board_id = $1`, boardID)
+	if err != nil {
+		return fmt.Errorf("error deleting mirror mapping: %v", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO hold_mirrors (board_id, hold_id, mirror_hold_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (hold_id) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("error preparing statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range pairs {
+		for _, pair := range [][2]uuid.UUID{{p.HoldID, p.MirrorHoldID}, {p.MirrorHoldID, p.HoldID}} {
+			_, err = stmt.Exec(boardID, pair[0], pair[1])
+			if err != nil {
+				return fmt.Errorf("error creating mirror pair: %v", err)
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// ClearBoardMirror marks a board as no longer symmetric and drops its mapping.
+func (d *DB) ClearBoardMirror(boardID uuid.UUID) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	_, err = tx.Exec(`
+		UPDATE boards
+		SET symmetric = FALSE, updated_at = NOW(), version = version + 1
+		WHERE id = $1
+	`, boardID)
+	if err != nil {
+		return fmt.Errorf("error updating board: %v", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM hold_mirrors WHERE board_id = $1`, boardID)
+	if err != nil {
+		return fmt.Errorf("error deleting mirror mapping: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetBoardMirror(boardID uuid.UUID) ([]MirrorPair, error) {
+	rows, err := d.Query(`
+		SELECT hold_id, mirror_hold_id
+		FROM hold_mirrors
+		WHERE board_id = $1
+		ORDER BY hold_id
+	`, boardID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying mirror mapping: %v", err)
+	}
+	defer rows.Close()
+
+	var pairs []MirrorPair
+
+	for rows.Next() {
+		var p MirrorPair
+
+		err := rows.Scan(&p.HoldID, &p.MirrorHoldID)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning mirror pair: %v", err)
+		}
+
+		pairs = append(pairs, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating mirror mapping: %v", err)
+	}
+
+	return pairs, nil
+}
+
+// MirrorProblemHolds maps each of a problem's holds to its mirror, keeping
+// its type. It fails with ErrNoMirror if any hold has no mirror.
+func MirrorProblemHolds(holds []ProblemHold, pairs []MirrorPair, boardHolds []Hold) ([]ProblemHold, error) {
+	mapping := make(map[uuid.UUID]uuid.UUID, len(pairs))
+	for _, p := range pairs {
+		mapping[p.HoldID] = p.MirrorHoldID
+	}
+
+	vertices := make(map[uuid.UUID][]Point, len(boardHolds))
+	for _, h := range boardHolds {
+		vertices[h.ID] = h.Vertices
+	}
+
+	mirrored := make([]ProblemHold, 0, len(holds))
+
+	for _, h := range holds {
+		mirrorID, ok := mapping[h.HoldID]
+		if !ok {
+			return nil, ErrNoMirror
+		}
+
+		mirrored = append(mirrored, ProblemHold{
+			ID:        h.ID,
+			ProblemID: h.ProblemID,
+			HoldID:    mirrorID,
+			Type:      h.Type,
+			Vertices:  vertices[mirrorID],
+		})
+	}
+
+	return mirrored, nil
+}
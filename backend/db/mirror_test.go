@@ -0,0 +1,72 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestComputeMirrorPairs(t *testing.T) {
+	var (
+		left   = uuid.MustParse("00000000-0000-0000-0000-000000000301")
+		right  = uuid.MustParse("00000000-0000-0000-0000-000000000302")
+		centre = uuid.MustParse("00000000-0000-0000-0000-000000000303")
+		nearby = uuid.MustParse("00000000-0000-0000-0000-000000000304")
+	)
+
+	// square is a small hold centred on (x, y)
+	square := func(id uuid.UUID, x, y float64) Hold {
+		const r = 0.01
+
+		return Hold{ID: id, Vertices: []Point{{x - r, y - r}, {x + r, y - r}, {x + r, y + r}, {x - r, y + r}}}
+	}
+
+	tests := []struct {
+		name          string
+		holds         []Hold
+		tolerance     float64
+		wantPairs     []MirrorPair
+		wantUnmatched []uuid.UUID
+	}{
+		{
+			name:      "reflected holds pair both ways",
+			holds:     []Hold{square(left, 0.2, 0.5), square(right, 0.8, 0.5)},
+			tolerance: 0.01,
+			wantPairs: []MirrorPair{{HoldID: left, MirrorHoldID: right}, {HoldID: right, MirrorHoldID: left}},
+		},
+		{
+			name:      "holds on the centre line mirror themselves",
+			holds:     []Hold{square(centre, 0.5, 0.3)},
+			tolerance: 0.01,
+			wantPairs: []MirrorPair{{HoldID: centre, MirrorHoldID: centre}},
+		},
+		{
+			name:          "holds outside the tolerance are unmatched",
+			holds:         []Hold{square(left, 0.2, 0.5), square(right, 0.85, 0.5)},
+			tolerance:     0.01,
+			wantUnmatched: []uuid.UUID{left, right},
+		},
+		{
+			name:          "closest candidate is paired first",
+			holds:         []Hold{square(left, 0.2, 0.5), square(nearby, 0.82, 0.5), square(right, 0.81, 0.5)},
+			tolerance:     0.05,
+			wantPairs:     []MirrorPair{{HoldID: left, MirrorHoldID: right}, {HoldID: right, MirrorHoldID: left}},
+			wantUnmatched: []uuid.UUID{nearby},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, unmatched := ComputeMirrorPairs(tt.holds, tt.tolerance)
+
+			if !reflect.DeepEqual(pairs, tt.wantPairs) {
+				t.Errorf("pairs: got %v, want %v", pairs, tt.wantPairs)
+			}
+
+			if !reflect.DeepEqual(unmatched, tt.wantUnmatched) {
+				t.Errorf("unmatched: got %v, want %v", unmatched, tt.wantUnmatched)
+			}
+		})
+	}
+}
@@ -0,0 +1,507 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+// OrganizationRole is what a member can do in an organization. Owners manage
+// its members, invites and boards; members can climb on its boards.
+type OrganizationRole string
+
+const (
+	OrganizationRoleOwner  OrganizationRole = "owner"
+	OrganizationRoleMember OrganizationRole = "member"
+)
+
+// Organization is a gym or household that owns boards. Role is the role of
+// the user it was fetched for.
+type Organization struct {
+	ID        uuid.UUID        `json:"id"`
+	Name      string           `json:"name"`
+	CreatedBy uuid.UUID        `json:"created_by"`
+	Role      OrganizationRole `json:"role,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+func (o Organization) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(o.Name != "", "name", "must be provided")
+	v.Check(len(o.Name) <= 100, "name", "must not be more than 100 characters long")
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+type OrganizationMember struct {
+	UserID   uuid.UUID        `json:"user_id"`
+	Role     OrganizationRole `json:"role"`
+	JoinedAt time.Time        `json:"joined_at"`
+}
+
+// OrganizationInvite asks a user to join an organization with a role. It is
+// removed once accepted, declined or revoked.
+type OrganizationInvite struct {
+	ID             uuid.UUID        `json:"id"`
+	OrganizationID uuid.UUID        `json:"organization_id"`
+	UserID         uuid.UUID        `json:"user_id"`
+	Role           OrganizationRole `json:"role"`
+	InvitedBy      uuid.UUID        `json:"invited_by"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+func (i OrganizationInvite) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(i.UserID != uuid.Nil, "user_id", "must be provided")
+	v.Check(
+		validator.PermittedValue(i.Role, OrganizationRoleOwner, OrganizationRoleMember),
+		"role", "must be one of owner or member",
+	)
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// CreateOrganization creates an organization with its creator as the owner.
+func (d *DB) CreateOrganization(o *Organization) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	err = tx.QueryRow(`
+		INSERT INTO organizations (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, o.Name, o.CreatedBy).Scan(&o.ID, &o.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating organization: %v", err)
+	}
+
+	o.Role = OrganizationRoleOwner
+
+	_, err = tx.Exec(`
+		INSERT INTO organization_members (organization_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, o.ID, o.CreatedBy, o.Role)
+	if err != nil {
+		return fmt.Errorf("error adding organization owner: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// GetOrganizations returns the organizations a user belongs to, with their
+// role in each.
+func (d *DB) GetOrganizations(userID uuid.UUID) ([]Organization, error) {
+	rows, err := d.Query(`
+		SELECT o.id, o.name, o.created_by, m.role, o.created_at
+		FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.name, o.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying organizations: %v", err)
+	}
+	defer rows.Close()
+
+	organizations := []Organization{}
+
+	for rows.Next() {
+		var o Organization
+
+		err := rows.Scan(&o.ID, &o.Name, &o.CreatedBy, &o.Role, &o.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning organization: %v", err)
+		}
+
+		organizations = append(organizations, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organizations: %v", err)
+	}
+
+	return organizations, nil
+}
+
+// GetOrganization returns an organization as seen by one of its members. It
+// returns ErrNotOrganizationMember if the user isn't one.
+func (d *DB) GetOrganization(organizationID, userID uuid.UUID) (*Organization, error) {
+	var (
+		o    Organization
+		role *OrganizationRole
+	)
+
+	err := d.QueryRow(`
+		SELECT o.id, o.name, o.created_by, m.role, o.created_at
+		FROM organizations o
+		LEFT JOIN organization_members m ON m.organization_id = o.id AND m.user_id = $2
+		WHERE o.id = $1
+	`, organizationID, userID).Scan(&o.ID, &o.Name, &o.CreatedBy, &role, &o.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrganizationNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error querying organization: %v", err)
+	}
+
+	if role == nil {
+		return nil, ErrNotOrganizationMember
+	}
+
+	o.Role = *role
+
+	return &o, nil
+}
+
+func (d *DB) GetOrganizationMembers(organizationID uuid.UUID) ([]OrganizationMember, error) {
+	rows, err := d.Query(`
+		SELECT user_id, role, joined_at
+		FROM organization_members
+		WHERE organization_id = $1
+		ORDER BY joined_at, user_id
+	`, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying organization members: %v", err)
+	}
+	defer rows.Close()
+
+	members := []OrganizationMember{}
+
+	for rows.Next() {
+		var m OrganizationMember
+
+		err := rows.Scan(&m.UserID, &m.Role, &m.JoinedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning organization member: %v", err)
+		}
+
+		members = append(members, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organization members: %v", err)
+	}
+
+	return members, nil
+}
+
+// rowQuerier is a *DB or *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// organizationRole returns a user's role in an organization, or
+// ErrOrganizationNotFound or ErrNotOrganizationMember.
+func organizationRole(q rowQuerier, organizationID, userID uuid.UUID) (OrganizationRole, error) {
+	var (
+		exists bool
+		role   *OrganizationRole
+	)
+
+	err := q.QueryRow(`
+		SELECT
+			EXISTS (SELECT 1 FROM organizations WHERE id = $1),
+			(SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2)
+	`, organizationID, userID).Scan(&exists, &role)
+	if err != nil {
+		return "", fmt.Errorf("error querying organization role: %v", err)
+	}
+
+	if !exists {
+		return "", ErrOrganizationNotFound
+	}
+
+	if role == nil {
+		return "", ErrNotOrganizationMember
+	}
+
+	return *role, nil
+}
+
+// GetOrganizationRole returns a user's role in an organization.
+func (d *DB) GetOrganizationRole(organizationID, userID uuid.UUID) (OrganizationRole, error) {
+	return organizationRole(d, organizationID, userID)
+}
+
+// RemoveOrganizationMember removes a member from an organization. Owners can
+// remove anyone and members can remove themselves, but an organization always
+// keeps at least one owner.
+func (d *DB) RemoveOrganizationMember(organizationID, memberID, userID uuid.UUID) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	// Lock the organization so two owners can't remove each other at once
+	_, err = tx.Exec(`SELECT id FROM organizations WHERE id = $1 FOR UPDATE`, organizationID)
+	if err != nil {
+		return fmt.Errorf("error locking organization: %v", err)
+	}
+
+	role, err := organizationRole(tx, organizationID, userID)
+	if err != nil {
+		return err
+	}
+
+	if memberID != userID && role != OrganizationRoleOwner {
+		return ErrNotOrganizationOwner
+	}
+
+	var (
+		memberRole OrganizationRole
+		owners     int
+	)
+
+	err = tx.QueryRow(`
+		SELECT role, (SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = 'owner')
+		FROM organization_members
+		WHERE organization_id = $1 AND user_id = $2
+	`, organizationID, memberID).Scan(&memberRole, &owners)
+	if err == sql.ErrNoRows {
+		return ErrNotOrganizationMember
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying organization member: %v", err)
+	}
+
+	if memberRole == OrganizationRoleOwner && owners == 1 {
+		return ErrLastOrganizationOwner
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2
+	`, organizationID, memberID)
+	if err != nil {
+		return fmt.Errorf("error removing organization member: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// CreateOrganizationInvite invites a user to an organization. Only its owners
+// can invite, and each user can only have one invite to it at a time.
+func (d *DB) CreateOrganizationInvite(i *OrganizationInvite) error {
+	role, err := d.GetOrganizationRole(i.OrganizationID, i.InvitedBy)
+	if err != nil {
+		return err
+	}
+
+	if role != OrganizationRoleOwner {
+		return ErrNotOrganizationOwner
+	}
+
+	_, err = d.GetOrganizationRole(i.OrganizationID, i.UserID)
+	if err == nil {
+		return ErrAlreadyOrganizationMember
+	}
+
+	if !errors.Is(err, ErrNotOrganizationMember) {
+		return err
+	}
+
+	err = d.QueryRow(`
+		INSERT INTO organization_invites (organization_id, user_id, role, invited_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, i.OrganizationID, i.UserID, i.Role, i.InvitedBy).Scan(&i.ID, &i.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "unique_organization_invite" {
+			return ErrAlreadyInvited
+		}
+
+		return fmt.Errorf("error creating organization invite: %v", err)
+	}
+
+	return nil
+}
+
+// GetOrganizationInvites returns an organization's pending invites.
+func (d *DB) GetOrganizationInvites(organizationID uuid.UUID) ([]OrganizationInvite, error) {
+	return d.getOrganizationInvites(`
+		SELECT id, organization_id, user_id, role, invited_by, created_at
+		FROM organization_invites
+		WHERE organization_id = $1
+		ORDER BY created_at, id
+	`, organizationID)
+}
+
+// GetUserInvites returns the invites a user hasn't answered yet.
+func (d *DB) GetUserInvites(userID uuid.UUID) ([]OrganizationInvite, error) {
+	return d.getOrganizationInvites(`
+		SELECT id, organization_id, user_id, role, invited_by, created_at
+		FROM organization_invites
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+}
+
+func (d *DB) getOrganizationInvites(query string, id uuid.UUID) ([]OrganizationInvite, error) {
+	rows, err := d.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying organization invites: %v", err)
+	}
+	defer rows.Close()
+
+	invites := []OrganizationInvite{}
+
+	for rows.Next() {
+		var i OrganizationInvite
+
+		err := rows.Scan(&i.ID, &i.OrganizationID, &i.UserID, &i.Role, &i.InvitedBy, &i.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning organization invite: %v", err)
+		}
+
+		invites = append(invites, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organization invites: %v", err)
+	}
+
+	return invites, nil
+}
+
+// AcceptOrganizationInvite makes a user a member of the organization they were
+// invited to, with the invite's role.
+func (d *DB) AcceptOrganizationInvite(inviteID, userID uuid.UUID) (*OrganizationMember, error) {
+	tx, err := d.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	var (
+		organizationID uuid.UUID
+		m              = OrganizationMember{UserID: userID}
+	)
+
+	err = tx.QueryRow(`
+		DELETE FROM organization_invites
+		WHERE id = $1 AND user_id = $2
+		RETURNING organization_id, role
+	`, inviteID, userID).Scan(&organizationID, &m.Role)
+	if err == sql.ErrNoRows {
+		return nil, ErrInviteNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error deleting organization invite: %v", err)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO organization_members (organization_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET role = organization_members.role
+		RETURNING role, joined_at
+	`, organizationID, userID, m.Role).Scan(&m.Role, &m.JoinedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error adding organization member: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return &m, nil
+}
+
+// DeleteOrganizationInvite removes an invite, which the invited user can do to
+// decline it and the organization's owners to revoke it.
+func (d *DB) DeleteOrganizationInvite(inviteID, userID uuid.UUID) error {
+	var invite OrganizationInvite
+
+	err := d.QueryRow(`
+		SELECT organization_id, user_id FROM organization_invites WHERE id = $1
+	`, inviteID).Scan(&invite.OrganizationID, &invite.UserID)
+	if err == sql.ErrNoRows {
+		return ErrInviteNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying organization invite: %v", err)
+	}
+
+	if invite.UserID != userID {
+		role, err := d.GetOrganizationRole(invite.OrganizationID, userID)
+		if errors.Is(err, ErrNotOrganizationMember) {
+			return ErrInviteNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if role != OrganizationRoleOwner {
+			return ErrNotOrganizationOwner
+		}
+	}
+
+	_, err = d.Exec(`DELETE FROM organization_invites WHERE id = $1`, inviteID)
+	if err != nil {
+		return fmt.Errorf("error deleting organization invite: %v", err)
+	}
+
+	return nil
+}
+
+// CanAccessBoard reports whether a user can see a board and everything on it:
+// it's public, theirs or one of their organizations' and not hidden, or they're
+// a moderator.
+func (d *DB) CanAccessBoard(boardID, userID uuid.UUID) (bool, error) {
+	var ok bool
+
+	err := d.QueryRow(`
+		SELECT
+			(b.hidden_at IS NULL AND (
+				b.public
+				OR b.owner_id = $2
+				OR EXISTS (SELECT 1 FROM organization_members m WHERE m.organization_id = b.organization_id AND m.user_id = $2)
+			))
+			OR EXISTS (SELECT 1 FROM moderators WHERE user_id = $2)
+		FROM boards b
+		WHERE b.id = $1
+	`, boardID, userID).Scan(&ok)
+	if err == sql.ErrNoRows {
+		return false, ErrBoardNotFound
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("error checking board access: %v", err)
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"math"
+
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+// Pagination selects one page of a list. Pages start at one.
+type Pagination struct {
+	Page     int
+	PageSize int
+}
+
+func (p Pagination) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(p.Page > 0, "page", "must be greater than zero")
+	v.Check(p.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+	v.Check(p.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(p.PageSize <= 100, "page_size", "must be a maximum of 100")
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+func (p Pagination) limit() int {
+	return p.PageSize
+}
+
+func (p Pagination) offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// Metadata describes the page that was returned and how many there are.
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records,omitempty"`
+}
+
+func calculateMetadata(totalRecords int, p Pagination) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  p.Page,
+		PageSize:     p.PageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(p.PageSize))),
+		TotalRecords: totalRecords,
+	}
+}
@@ -7,13 +7,17 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/validator"
 )
 
 type ProblemStatus string
 
 const (
 	ProblemStatusDraft     ProblemStatus = "DRAFT"
+	ProblemStatusInReview  ProblemStatus = "IN_REVIEW"
 	ProblemStatusPublished ProblemStatus = "PUBLISHED"
+	ProblemStatusArchived  ProblemStatus = "ARCHIVED"
 )
 
 type HoldType string
@@ -21,19 +25,36 @@ type HoldType string
 const (
 	HoldTypeStart  HoldType = "start"
 	HoldTypeHand   HoldType = "hand"
+	HoldTypeZone   HoldType = "zone" // a hand hold that scores a zone in competitions
 	HoldTypeFoot   HoldType = "foot"
 	HoldTypeFinish HoldType = "finish"
 )
 
+// ProblemRules are the movement restrictions a setter attaches to a problem,
+// on top of the holds themselves.
+type ProblemRules struct {
+	FeetFollowHands bool `json:"feet_follow_hands"`
+	NoMatching      bool `json:"no_matching"`
+	Campus          bool `json:"campus"`
+	FootlessStart   bool `json:"footless_start"`
+	MarkedFeetOnly  bool `json:"marked_feet_only"`
+}
+
 type Problem struct {
 	ID        uuid.UUID     `json:"id"`
 	BoardID   uuid.UUID     `json:"board_id"`
 	Name      string        `json:"name"`
 	SetterID  uuid.UUID     `json:"setter_id"`
 	Status    ProblemStatus `json:"status"`
+	Grade     *int          `json:"grade"`
+	Rules     ProblemRules  `json:"rules"`
+	ParentID  *uuid.UUID    `json:"parent_id"`
 	CreatedAt time.Time     `json:"created_at"`
 }
 
+// MaxGrade is the hardest grade on the V scale that problems can be given.
+const MaxGrade = 17
+
 type ProblemHold struct {
 	ID        uuid.UUID `json:"id"`
 	ProblemID uuid.UUID `json:"problemID"`
@@ -42,6 +63,107 @@ type ProblemHold struct {
 	Vertices  []Point   `json:"vertices"`
 }
 
+// Validate checks a problem hold on its own. Keys match the hold objects
+// clients send, where "id" is the board hold being used.
+func (h ProblemHold) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(h.HoldID != uuid.Nil, "id", "must be provided")
+	v.Check(
+		validator.PermittedValue(h.Type, HoldTypeStart, HoldTypeHand, HoldTypeZone, HoldTypeFoot, HoldTypeFinish),
+		"type", "must be one of start, hand, zone, foot or finish",
+	)
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// Validate checks a problem and its holds, which must all be distinct holds
+// from boardHolds.
+func (p Problem) Validate(holds []ProblemHold, boardHolds []Hold) map[string]string {
+	v := validator.New()
+
+	v.Check(p.Name != "", "name", "must be provided")
+	v.Check(len(p.Name) <= 255, "name", "must not be more than 255 bytes long")
+	v.Check(
+		validator.PermittedValue(p.Status, ProblemStatusDraft, ProblemStatusInReview, ProblemStatusPublished, ProblemStatusArchived),
+		"status", "must be one of DRAFT, IN_REVIEW, PUBLISHED or ARCHIVED",
+	)
+	v.Check(len(holds) >= 3, "holds", "must contain at least 3 holds")
+
+	if p.Grade != nil {
+		v.Check(*p.Grade >= 0 && *p.Grade <= MaxGrade, "grade", fmt.Sprintf("must be between 0 and %d", MaxGrade))
+	}
+
+	onBoard := make(map[uuid.UUID]bool, len(boardHolds))
+	for _, h := range boardHolds {
+		onBoard[h.ID] = true
+	}
+
+	seen := make(map[uuid.UUID]bool, len(holds))
+	counts := make(map[HoldType]int)
+
+	for i, h := range holds {
+		key := fmt.Sprintf("holds[%d]", i)
+
+		v.AddErrors(key, h.Validate())
+		v.Check(!seen[h.HoldID], key+".id", "must not be listed more than once")
+		v.Check(onBoard[h.HoldID], key+".id", "must be a hold on the board")
+
+		seen[h.HoldID] = true
+		counts[h.Type]++
+	}
+
+	v.Check(counts[HoldTypeStart] == 1 || counts[HoldTypeStart] == 2, "start_holds", "must have exactly 1 or 2 start holds")
+	v.Check(counts[HoldTypeFinish] >= 1, "finish_holds", "must have at least 1 finish hold")
+	v.Check(counts[HoldTypeZone] <= 1, "zone_holds", "must not have more than 1 zone hold")
+
+	footHolds := counts[HoldTypeFoot]
+
+	switch {
+	case p.Rules.Campus && footHolds > 0:
+		v.AddError("rules.campus", "campus problems must not have foot holds")
+	case p.Rules.Campus && (p.Rules.FeetFollowHands || p.Rules.MarkedFeetOnly):
+		v.AddError("rules.campus", "campus problems cannot have foot rules")
+	case p.Rules.FeetFollowHands && p.Rules.MarkedFeetOnly:
+		v.AddError("rules.feet_follow_hands", "cannot be combined with marked_feet_only")
+	case p.Rules.FeetFollowHands && footHolds > 0:
+		v.AddError("rules.feet_follow_hands", "feet follow hands problems must not have foot holds")
+	}
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// problemColumns are the columns scanned by scanProblem, in order.
+const problemColumns = `
+	id, board_id, name, setter_id, status, grade,
+	feet_follow_hands, no_matching, campus, footless_start, marked_feet_only,
+	parent_id, created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanProblem scans problemColumns into p, followed by any extra columns the
+// query selected after them into extra.
+func scanProblem(row rowScanner, p *Problem, extra ...any) error {
+	dest := []any{
+		&p.ID, &p.BoardID, &p.Name, &p.SetterID, &p.Status, &p.Grade,
+		&p.Rules.FeetFollowHands, &p.Rules.NoMatching, &p.Rules.Campus,
+		&p.Rules.FootlessStart, &p.Rules.MarkedFeetOnly,
+		&p.ParentID, &p.CreatedAt,
+	}
+
+	return row.Scan(append(dest, extra...)...) //nolint:wrapcheck
+}
+
 func (d *DB) CreateProblem(boardID uuid.UUID, problem *Problem, holds []ProblemHold) error {
 	tx, err := d.Begin()
 	if err != nil {
@@ -52,10 +174,19 @@ func (d *DB) CreateProblem(boardID uuid.UUID, problem *Problem, holds []ProblemH
 
 	// Insert problem
 	err = tx.QueryRow(`
-		INSERT INTO problems (id, board_id, name, setter_id, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, NOW())
+		INSERT INTO problems (
+			id, board_id, name, setter_id, status, grade,
+			feet_follow_hands, no_matching, campus, footless_start, marked_feet_only,
+			parent_id, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
 		RETURNING created_at
-	`, problem.ID, boardID, problem.Name, problem.SetterID, problem.Status).Scan(&problem.CreatedAt)
+	`,
+		problem.ID, boardID, problem.Name, problem.SetterID, problem.Status, problem.Grade,
+		problem.Rules.FeetFollowHands, problem.Rules.NoMatching, problem.Rules.Campus,
+		problem.Rules.FootlessStart, problem.Rules.MarkedFeetOnly,
+		problem.ParentID,
+	).Scan(&problem.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("error creating problem: %v", err)
 	}
@@ -77,6 +208,16 @@ func (d *DB) CreateProblem(boardID uuid.UUID, problem *Problem, holds []ProblemH
 		}
 	}
 
+	err = insertProblemRevision(tx, problem, holds, problem.SetterID)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhooks(tx, events.Event{Type: events.ProblemCreated, BoardID: boardID, Data: problem}, problem.Grade)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("error committing transaction: %v", err)
@@ -85,13 +226,16 @@ func (d *DB) CreateProblem(boardID uuid.UUID, problem *Problem, holds []ProblemH
 	return nil
 }
 
-func (d *DB) GetProblems(boardID uuid.UUID) ([]Problem, error) {
+// GetProblems returns the problems on a board, newest first. Archived problems
+// are left out unless includeArchived is set, and problems hidden by a
+// moderator unless includeHidden is set.
+func (d *DB) GetProblems(boardID uuid.UUID, includeArchived, includeHidden bool) ([]Problem, error) {
 	rows, err := d.Query(`
-		SELECT id, board_id, name, status, created_at
+		SELECT `+problemColumns+`
 		FROM problems
-		WHERE board_id = $1
+		WHERE board_id = $1 AND ($2 OR status <> 'ARCHIVED') AND ($3 OR hidden_at IS NULL)
 		ORDER BY created_at DESC
-	`, boardID)
+	`, boardID, includeArchived, includeHidden)
 	if err != nil {
 		return nil, fmt.Errorf("error querying problems: %v", err)
 	}
@@ -102,7 +246,7 @@ func (d *DB) GetProblems(boardID uuid.UUID) ([]Problem, error) {
 	for rows.Next() {
 		var p Problem
 
-		err := rows.Scan(&p.ID, &p.BoardID, &p.Name, &p.Status, &p.CreatedAt)
+		err := scanProblem(rows, &p)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning problem: %v", err)
 		}
@@ -119,11 +263,49 @@ func (d *DB) GetProblems(boardID uuid.UUID) ([]Problem, error) {
 
 func (d *DB) GetProblem(boardID, problemID uuid.UUID) (*Problem, error) {
 	var p Problem
-	err := d.QueryRow(`
-		SELECT id, board_id, name, status, created_at
+
+	err := scanProblem(d.QueryRow(`
+		SELECT `+problemColumns+`
 		FROM problems
 		WHERE id = $1 AND board_id = $2
-	`, problemID, boardID).Scan(&p.ID, &p.BoardID, &p.Name, &p.Status, &p.CreatedAt)
+	`, problemID, boardID), &p)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrProblemNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error querying problem: %v", err)
+	}
+
+	return &p, nil
+}
+
+// IsProblemHidden reports whether a moderator has hidden a problem.
+func (d *DB) IsProblemHidden(problemID uuid.UUID) (bool, error) {
+	var hidden bool
+
+	err := d.QueryRow(`SELECT hidden_at IS NOT NULL FROM problems WHERE id = $1`, problemID).Scan(&hidden)
+	if err == sql.ErrNoRows {
+		return false, ErrProblemNotFound
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("error querying problem: %v", err)
+	}
+
+	return hidden, nil
+}
+
+// GetProblemByID returns a problem from whichever board it is on.
+func (d *DB) GetProblemByID(problemID uuid.UUID) (*Problem, error) {
+	var p Problem
+
+	err := scanProblem(d.QueryRow(`
+		SELECT `+problemColumns+`
+		FROM problems
+		WHERE id = $1
+	`, problemID), &p)
 
 	if err == sql.ErrNoRows {
 		return nil, ErrProblemNotFound
@@ -136,6 +318,82 @@ func (d *DB) GetProblem(boardID, problemID uuid.UUID) (*Problem, error) {
 	return &p, nil
 }
 
+// GetProblemLineage returns the problems a problem was forked from, starting
+// with its parent and ending with the original.
+func (d *DB) GetProblemLineage(problemID uuid.UUID) ([]Problem, error) {
+	rows, err := d.Query(`
+		WITH RECURSIVE ancestors AS (
+			SELECT p.*, 1 AS depth
+			FROM problems p
+			WHERE p.id = (SELECT parent_id FROM problems WHERE id = $1)
+			UNION ALL
+			SELECT p.*, a.depth + 1
+			FROM problems p
+			JOIN ancestors a ON p.id = a.parent_id
+		)
+		SELECT `+problemColumns+`
+		FROM ancestors
+		ORDER BY depth
+	`, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying problem lineage: %v", err)
+	}
+	defer rows.Close()
+
+	var lineage []Problem
+
+	for rows.Next() {
+		var p Problem
+
+		err := scanProblem(rows, &p)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning problem: %v", err)
+		}
+
+		lineage = append(lineage, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating problem lineage: %v", err)
+	}
+
+	return lineage, nil
+}
+
+// GetProblemForks returns the problems forked directly from a problem, oldest
+// first.
+func (d *DB) GetProblemForks(problemID uuid.UUID) ([]Problem, error) {
+	rows, err := d.Query(`
+		SELECT `+problemColumns+`
+		FROM problems
+		WHERE parent_id = $1
+		ORDER BY created_at
+	`, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying problem forks: %v", err)
+	}
+	defer rows.Close()
+
+	var forks []Problem
+
+	for rows.Next() {
+		var p Problem
+
+		err := scanProblem(rows, &p)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning problem: %v", err)
+		}
+
+		forks = append(forks, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating problem forks: %v", err)
+	}
+
+	return forks, nil
+}
+
 func (d *DB) GetProblemHolds(problemID uuid.UUID) ([]ProblemHold, error) {
 	rows, err := d.Query(`
 		SELECT 
@@ -184,14 +442,29 @@ func (d *DB) GetProblemHolds(problemID uuid.UUID) ([]ProblemHold, error) {
 	return holds, nil
 }
 
-func (d *DB) UpdateProblem(boardID uuid.UUID, problem *Problem, holds []ProblemHold) error {
-	// Check if problem exists and is in draft status
-	var status ProblemStatus
-	err := d.QueryRow(`
-		SELECT status
+// UpdateProblem replaces a draft problem's details and holds, and records the
+// result as a new revision made by editorID, who must be the problem's setter.
+func (d *DB) UpdateProblem(boardID uuid.UUID, problem *Problem, holds []ProblemHold, editorID uuid.UUID) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	// Check if problem exists and is in draft status, and hold it there until
+	// the edit and its revision are committed
+	var (
+		status   ProblemStatus
+		setterID uuid.UUID
+	)
+
+	err = tx.QueryRow(`
+		SELECT status, setter_id
 		FROM problems
 		WHERE id = $1 AND board_id = $2
-	`, problem.ID, boardID).Scan(&status)
+		FOR UPDATE
+	`, problem.ID, boardID).Scan(&status, &setterID)
 
 	if err == sql.ErrNoRows {
 		return ErrProblemNotFound
@@ -201,23 +474,29 @@ func (d *DB) UpdateProblem(boardID uuid.UUID, problem *Problem, holds []ProblemH
 		return fmt.Errorf("error querying problem: %v", err)
 	}
 
-	if status != ProblemStatusDraft {
-		return fmt.Errorf("cannot edit published problem")
+	if editorID != setterID {
+		return ErrNotProblemSetter
 	}
 
-	tx, err := d.Begin()
-	if err != nil {
-		return fmt.Errorf("error beginning transaction: %v", err)
+	if status != ProblemStatusDraft {
+		return ErrProblemNotEditable
 	}
 
-	defer tx.Rollback() //nolint:errcheck
-
-	// Update problem
-	_, err = tx.Exec(`
+	// Update problem. Status only changes through TransitionProblem.
+	err = tx.QueryRow(`
 		UPDATE problems
-		SET name = $1, status = $2
-		WHERE id = $3 AND board_id = $4
-	`, problem.Name, problem.Status, problem.ID, boardID)
+		SET
+			name = $1, grade = $2,
+			feet_follow_hands = $3, no_matching = $4, campus = $5,
+			footless_start = $6, marked_feet_only = $7
+		WHERE id = $8 AND board_id = $9 AND status = 'DRAFT'
+		RETURNING setter_id, status, created_at
+	`,
+		problem.Name, problem.Grade,
+		problem.Rules.FeetFollowHands, problem.Rules.NoMatching, problem.Rules.Campus,
+		problem.Rules.FootlessStart, problem.Rules.MarkedFeetOnly,
+		problem.ID, boardID,
+	).Scan(&problem.SetterID, &problem.Status, &problem.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("error updating problem: %v", err)
 	}
@@ -248,6 +527,16 @@ func (d *DB) UpdateProblem(boardID uuid.UUID, problem *Problem, holds []ProblemH
 		}
 	}
 
+	err = insertProblemRevision(tx, problem, holds, editorID)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhooks(tx, events.Event{Type: events.ProblemUpdated, BoardID: boardID, Data: problem}, problem.Grade)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("error committing transaction: %v", err)
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Project is a problem a user is working on, with their progress on it so far.
+// Sessions counts the separate sessions they have tried it in.
+type Project struct {
+	Problem       Problem    `json:"problem"`
+	Attempts      int        `json:"attempts"`
+	Sessions      int        `json:"sessions"`
+	LastAttemptAt *time.Time `json:"last_attempt_at"`
+	AddedAt       time.Time  `json:"added_at"`
+}
+
+// AddProject puts a problem on a user's project list. Problems they have
+// already sent can't become projects.
+func (d *DB) AddProject(userID, problemID uuid.UUID) error {
+	var sent bool
+
+	err := d.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM attempts
+			WHERE user_id = $1 AND problem_id = $2 AND status = 'sent'
+		)
+	`, userID, problemID).Scan(&sent)
+	if err != nil {
+		return fmt.Errorf("error checking sends: %v", err)
+	}
+
+	if sent {
+		return ErrAlreadySent
+	}
+
+	_, err = d.Exec(`
+		INSERT INTO projects (user_id, problem_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, userID, problemID)
+	if err != nil {
+		return fmt.Errorf("error adding project: %v", err)
+	}
+
+	return nil
+}
+
+func (d *DB) RemoveProject(userID, problemID uuid.UUID) error {
+	_, err := d.Exec(`DELETE FROM projects WHERE user_id = $1 AND problem_id = $2`, userID, problemID)
+	if err != nil {
+		return fmt.Errorf("error removing project: %v", err)
+	}
+
+	return nil
+}
+
+// GetProjects returns a user's projects, most recently tried first. Problems
+// on boards they can no longer see, or that a moderator has since hidden, are
+// left out.
+func (d *DB) GetProjects(userID uuid.UUID) ([]Project, error) {
+	rows, err := d.Query(`
+		SELECT `+problemColumns+`,
+			COALESCE(a.attempts, 0), COALESCE(a.sessions, 0), a.last_attempt_at, pr.added_at
+		FROM problems
+		JOIN (
+			SELECT problem_id, created_at AS added_at
+			FROM projects
+			WHERE user_id = $1
+		) pr ON pr.problem_id = problems.id
+		LEFT JOIN (
+			SELECT problem_id,
+				COUNT(*) AS attempts,
+				COUNT(DISTINCT session_id) AS sessions,
+				MAX(attempted_at) AS last_attempt_at
+			FROM attempts
+			WHERE user_id = $1
+			GROUP BY problem_id
+		) a ON a.problem_id = problems.id
+		WHERE board_visible_to(problems.board_id, $1)
+			AND (problems.hidden_at IS NULL OR EXISTS (SELECT 1 FROM moderators WHERE user_id = $1))
+		ORDER BY COALESCE(a.last_attempt_at, pr.added_at) DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying projects: %v", err)
+	}
+	defer rows.Close()
+
+	var projects []Project
+
+	for rows.Next() {
+		var pr Project
+
+		err := scanProblem(rows, &pr.Problem, &pr.Attempts, &pr.Sessions, &pr.LastAttemptAt, &pr.AddedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning project: %v", err)
+		}
+
+		projects = append(projects, pr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating projects: %v", err)
+	}
+
+	return projects, nil
+}
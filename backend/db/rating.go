@@ -0,0 +1,45 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+// Rating is how much a user enjoyed a problem, from one to five stars.
+type Rating struct {
+	ProblemID uuid.UUID `json:"problem_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Stars     int       `json:"stars"`
+	RatedAt   time.Time `json:"rated_at"`
+}
+
+func (r Rating) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(r.Stars >= 1 && r.Stars <= 5, "stars", "must be between 1 and 5")
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// RateProblem records a user's rating, replacing any earlier rating they gave
+// the same problem.
+func (d *DB) RateProblem(r *Rating) error {
+	err := d.QueryRow(`
+		INSERT INTO problem_ratings (problem_id, user_id, stars)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (problem_id, user_id) DO UPDATE SET stars = EXCLUDED.stars, rated_at = NOW()
+		RETURNING rated_at
+	`, r.ProblemID, r.UserID, r.Stars).Scan(&r.RatedAt)
+	if err != nil {
+		return fmt.Errorf("error rating problem: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// RecommendationCandidate is a published problem the user hasn't sent, with
+// the community stats used to rank it.
+type RecommendationCandidate struct {
+	Problem      Problem
+	Holds        []ProblemHold
+	AverageStars float64
+	Ratings      int
+	Climbers     int
+}
+
+// RecommendationInput is everything known about a user's climbing that
+// RankRecommendations needs.
+type RecommendationInput struct {
+	Candidates []RecommendationCandidate
+	// SendGrades has the grade of every graded problem the user has sent.
+	SendGrades []int
+	// Enjoyed has the holds of problems the user rated highly, or of problems
+	// they have sent when they haven't rated any.
+	Enjoyed [][]ProblemHold
+}
+
+type RecommendationScores struct {
+	GradeFit   float64 `json:"grade_fit"`
+	Rating     float64 `json:"rating"`
+	Popularity float64 `json:"popularity"`
+	Similarity float64 `json:"similarity"`
+}
+
+type Recommendation struct {
+	Problem Problem              `json:"problem"`
+	Score   float64              `json:"score"`
+	Scores  RecommendationScores `json:"scores"`
+}
+
+// Weights of each score in a recommendation. Grade fit counts most, since a
+// great problem two grades too hard is no use today.
+const (
+	gradeFitWeight   = 0.4
+	ratingWeight     = 0.2
+	popularityWeight = 0.2
+	similarityWeight = 0.2
+)
+
+// RankRecommendations scores candidates for a user and returns the best limit
+// of them. Each score is between 0 and 1:
+//
+//   - grade fit peaks just above the average of the user's five hardest sends
+//   - rating is the average stars, pulled towards three stars when there are
+//     few ratings
+//   - popularity is how many people have tried the problem, on a log scale
+//   - similarity is the closest match to a problem the user enjoyed
+func RankRecommendations(in RecommendationInput, limit int) []Recommendation {
+	grades := append([]int(nil), in.SendGrades...)
+	sort.Sort(sort.Reverse(sort.IntSlice(grades)))
+
+	target := 0.5
+
+	if len(grades) > 0 {
+		top := grades[:min(5, len(grades))]
+
+		sum := 0
+		for _, g := range top {
+			sum += g
+		}
+
+		target = float64(sum)/float64(len(top)) + 0.5
+	}
+
+	maxClimbers := 0
+	for _, c := range in.Candidates {
+		maxClimbers = max(maxClimbers, c.Climbers)
+	}
+
+	recommendations := make([]Recommendation, 0, len(in.Candidates))
+
+	for _, c := range in.Candidates {
+		var s RecommendationScores
+
+		s.GradeFit = 0.5
+		if c.Problem.Grade != nil {
+			diff := float64(*c.Problem.Grade) - target
+			s.GradeFit = math.Exp(-diff * diff / (2 * 1.5 * 1.5))
+		}
+
+		const priorRatings, priorStars = 2, 3
+
+		s.Rating = (c.AverageStars*float64(c.Ratings) + priorStars*priorRatings) / float64(c.Ratings+priorRatings) / 5
+
+		if maxClimbers > 0 {
+			s.Popularity = math.Log1p(float64(c.Climbers)) / math.Log1p(float64(maxClimbers))
+		}
+
+		for _, enjoyed := range in.Enjoyed {
+			s.Similarity = math.Max(s.Similarity, HoldSetSimilarity(c.Holds, enjoyed))
+		}
+
+		recommendations = append(recommendations, Recommendation{
+			Problem: c.Problem,
+			Score: gradeFitWeight*s.GradeFit +
+				ratingWeight*s.Rating +
+				popularityWeight*s.Popularity +
+				similarityWeight*s.Similarity,
+			Scores: s,
+		})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool { return recommendations[i].Score > recommendations[j].Score })
+
+	if limit > 0 && len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	return recommendations
+}
+
+// GetRecommendationInput gathers a user's sends and ratings, and the published,
+// unhidden problems they haven't sent on boards they can see, optionally only
+// on one board.
+func (d *DB) GetRecommendationInput(userID uuid.UUID, boardID *uuid.UUID) (*RecommendationInput, error) {
+	var in RecommendationInput
+
+	rows, err := d.Query(`
+		SELECT `+problemColumns+`,
+			COALESCE(r.average_stars, 0), COALESCE(r.ratings, 0), COALESCE(c.climbers, 0)
+		FROM problems p
+		LEFT JOIN (
+			SELECT problem_id, AVG(stars)::float8 AS average_stars, COUNT(*) AS ratings
+			FROM problem_ratings
+			GROUP BY problem_id
+		) r ON r.problem_id = p.id
+		LEFT JOIN (
+			SELECT problem_id, COUNT(DISTINCT user_id) AS climbers
+			FROM attempts
+			GROUP BY problem_id
+		) c ON c.problem_id = p.id
+		WHERE p.status = 'PUBLISHED'
+			AND p.hidden_at IS NULL
+			AND ($2::uuid IS NULL OR p.board_id = $2)
+			AND board_visible_to(p.board_id, $1)
+			AND NOT EXISTS (
+				SELECT 1 FROM attempts a
+				WHERE a.problem_id = p.id AND a.user_id = $1 AND a.status = 'sent'
+			)
+	`, userID, boardID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying recommendation candidates: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c RecommendationCandidate
+
+		err := scanProblem(rows, &c.Problem, &c.AverageStars, &c.Ratings, &c.Climbers)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning recommendation candidate: %v", err)
+		}
+
+		in.Candidates = append(in.Candidates, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recommendation candidates: %v", err)
+	}
+
+	gradeRows, err := d.Query(`
+		SELECT DISTINCT p.id, p.grade
+		FROM attempts a
+		JOIN problems p ON p.id = a.problem_id
+		WHERE a.user_id = $1 AND a.status = 'sent' AND p.grade IS NOT NULL
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying sent grades: %v", err)
+	}
+	defer gradeRows.Close()
+
+	for gradeRows.Next() {
+		var (
+			id    uuid.UUID
+			grade int
+		)
+
+		err := gradeRows.Scan(&id, &grade)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning sent grade: %v", err)
+		}
+
+		in.SendGrades = append(in.SendGrades, grade)
+	}
+
+	if err = gradeRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sent grades: %v", err)
+	}
+
+	var enjoyedIDs []string
+
+	err = d.QueryRow(`
+		SELECT COALESCE(
+			(SELECT array_agg(problem_id::text) FROM problem_ratings WHERE user_id = $1 AND stars >= 4),
+			(SELECT array_agg(DISTINCT problem_id::text) FROM attempts WHERE user_id = $1 AND status = 'sent'),
+			'{}'
+		)
+	`, userID).Scan(pq.Array(&enjoyedIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error querying enjoyed problems: %v", err)
+	}
+
+	ids := append([]string(nil), enjoyedIDs...)
+	for _, c := range in.Candidates {
+		ids = append(ids, c.Problem.ID.String())
+	}
+
+	holdSets, err := d.getHoldSets(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range in.Candidates {
+		in.Candidates[i].Holds = holdSets[in.Candidates[i].Problem.ID]
+	}
+
+	for _, id := range enjoyedIDs {
+		in.Enjoyed = append(in.Enjoyed, holdSets[uuid.MustParse(id)])
+	}
+
+	return &in, nil
+}
+
+// getHoldSets loads the hold IDs and types of many problems at once.
+func (d *DB) getHoldSets(problemIDs []string) (map[uuid.UUID][]ProblemHold, error) {
+	rows, err := d.Query(`
+		SELECT problem_id, hold_id, type
+		FROM problem_holds
+		WHERE problem_id = ANY($1::uuid[])
+	`, pq.Array(problemIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error querying problem holds: %v", err)
+	}
+	defer rows.Close()
+
+	holdSets := make(map[uuid.UUID][]ProblemHold)
+
+	for rows.Next() {
+		var h ProblemHold
+
+		err := rows.Scan(&h.ProblemID, &h.HoldID, &h.Type)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning problem hold: %v", err)
+		}
+
+		holdSets[h.ProblemID] = append(holdSets[h.ProblemID], h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating problem holds: %v", err)
+	}
+
+	return holdSets, nil
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRankRecommendations(t *testing.T) {
+	hold := uuid.MustParse("00000000-0000-0000-0000-000000000501")
+	enjoyed := []ProblemHold{{HoldID: hold, Type: HoldTypeStart}}
+
+	// candidate is an unrated problem nobody has tried
+	candidate := func(name string, grade ...int) RecommendationCandidate {
+		c := RecommendationCandidate{Problem: Problem{Name: name}}
+		if len(grade) > 0 {
+			c.Problem.Grade = &grade[0]
+		}
+
+		return c
+	}
+
+	rated := func(c RecommendationCandidate, stars float64, ratings int) RecommendationCandidate {
+		c.AverageStars, c.Ratings = stars, ratings
+		return c
+	}
+
+	climbed := func(c RecommendationCandidate, climbers int) RecommendationCandidate {
+		c.Climbers = climbers
+		return c
+	}
+
+	holding := func(c RecommendationCandidate, holds []ProblemHold) RecommendationCandidate {
+		c.Holds = holds
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		in    RecommendationInput
+		limit int
+		want  []string
+	}{
+		{
+			name: "grade just above the user's level first",
+			in: RecommendationInput{
+				Candidates: []RecommendationCandidate{candidate("V9", 9), candidate("ungraded"), candidate("V5", 5)},
+				SendGrades: []int{4, 4, 4},
+			},
+			want: []string{"V5", "ungraded", "V9"},
+		},
+		{
+			name: "only the five hardest sends set the level",
+			in: RecommendationInput{
+				Candidates: []RecommendationCandidate{candidate("V2", 2), candidate("V7", 7)},
+				SendGrades: []int{1, 1, 1, 7, 7, 7, 7, 7},
+			},
+			want: []string{"V7", "V2"},
+		},
+		{
+			name: "beginners get easy problems",
+			in: RecommendationInput{
+				Candidates: []RecommendationCandidate{candidate("V4", 4), candidate("V1", 1)},
+			},
+			want: []string{"V1", "V4"},
+		},
+		{
+			name: "few ratings are pulled towards three stars",
+			in: RecommendationInput{
+				Candidates: []RecommendationCandidate{rated(candidate("one five", 3), 5, 1), rated(candidate("many good", 3), 4.5, 10)},
+				SendGrades: []int{3},
+			},
+			want: []string{"many good", "one five"},
+		},
+		{
+			name: "popular problems first",
+			in: RecommendationInput{
+				Candidates: []RecommendationCandidate{candidate("quiet", 3), climbed(candidate("busy", 3), 9)},
+				SendGrades: []int{3},
+			},
+			want: []string{"busy", "quiet"},
+		},
+		{
+			name: "problems like ones the user enjoyed first",
+			in: RecommendationInput{
+				Candidates: []RecommendationCandidate{candidate("different", 3), holding(candidate("similar", 3), enjoyed)},
+				SendGrades: []int{3},
+				Enjoyed:    [][]ProblemHold{enjoyed},
+			},
+			want: []string{"similar", "different"},
+		},
+		{
+			name: "limit keeps the best",
+			in: RecommendationInput{
+				Candidates: []RecommendationCandidate{candidate("V9", 9), candidate("V5", 5), candidate("V4", 4)},
+				SendGrades: []int{4},
+			},
+			limit: 2,
+			want:  []string{"V5", "V4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recommendations := RankRecommendations(tt.in, tt.limit)
+
+			got := make([]string, len(recommendations))
+
+			for i, r := range recommendations {
+				got[i] = r.Problem.Name
+
+				for name, score := range map[string]float64{
+					"score":      r.Score,
+					"grade_fit":  r.Scores.GradeFit,
+					"rating":     r.Scores.Rating,
+					"popularity": r.Scores.Popularity,
+					"similarity": r.Scores.Similarity,
+				} {
+					if score < 0 || score > 1 {
+						t.Errorf("%s: %s is %f, want between 0 and 1", r.Problem.Name, name, score)
+					}
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+type ReportTarget string
+
+const (
+	ReportTargetProblem ReportTarget = "problem"
+	ReportTargetComment ReportTarget = "comment"
+	ReportTargetBoard   ReportTarget = "board"
+)
+
+type ReportReason string
+
+const (
+	ReportReasonSpam          ReportReason = "spam"
+	ReportReasonOffensive     ReportReason = "offensive"
+	ReportReasonInappropriate ReportReason = "inappropriate"
+	ReportReasonOther         ReportReason = "other"
+)
+
+type ReportStatus string
+
+const (
+	ReportStatusOpen      ReportStatus = "open"
+	ReportStatusActioned  ReportStatus = "actioned"
+	ReportStatusDismissed ReportStatus = "dismissed"
+)
+
+type ModerationAction string
+
+const (
+	ModerationActionHide    ModerationAction = "hide"
+	ModerationActionDelete  ModerationAction = "delete"
+	ModerationActionDismiss ModerationAction = "dismiss"
+)
+
+// MaxReportDetailsLength is the longest explanation a reporter can give, in
+// bytes.
+const MaxReportDetailsLength = 1000
+
+// Report flags a problem, comment or board for moderators to review. BoardID
+// is the board the content is on, which decides who can moderate it.
+type Report struct {
+	ID         uuid.UUID         `json:"id"`
+	TargetType ReportTarget      `json:"target_type"`
+	TargetID   uuid.UUID         `json:"target_id"`
+	BoardID    uuid.UUID         `json:"board_id"`
+	ReporterID uuid.UUID         `json:"reporter_id"`
+	Reason     ReportReason      `json:"reason"`
+	Details    string            `json:"details"`
+	Status     ReportStatus      `json:"status"`
+	Action     *ModerationAction `json:"action"`
+	ResolvedBy *uuid.UUID        `json:"resolved_by"`
+	ResolvedAt *time.Time        `json:"resolved_at"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func (r Report) Validate() map[string]string {
+	v := validator.New()
+
+	v.Check(
+		validator.PermittedValue(r.TargetType, ReportTargetProblem, ReportTargetComment, ReportTargetBoard),
+		"target_type", "must be one of problem, comment or board",
+	)
+	v.Check(r.TargetID != uuid.Nil, "target_id", "must be provided")
+	v.Check(
+		validator.PermittedValue(r.Reason, ReportReasonSpam, ReportReasonOffensive, ReportReasonInappropriate, ReportReasonOther),
+		"reason", "must be one of spam, offensive, inappropriate or other",
+	)
+	v.Check(r.Reason != ReportReasonOther || r.Details != "", "details", "must be provided when the reason is other")
+	v.Check(len(r.Details) <= MaxReportDetailsLength, "details", fmt.Sprintf("must not be more than %d bytes long", MaxReportDetailsLength))
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// IsModerator reports whether a user is a site-wide moderator.
+func (d *DB) IsModerator(userID uuid.UUID) (bool, error) {
+	var moderator bool
+
+	err := d.QueryRow(`SELECT EXISTS(SELECT 1 FROM moderators WHERE user_id = $1)`, userID).Scan(&moderator)
+	if err != nil {
+		return false, fmt.Errorf("error checking moderator: %v", err)
+	}
+
+	return moderator, nil
+}
+
+// CreateReport files a report. Each user can only have one open report on the
+// same content.
+func (d *DB) CreateReport(r *Report) error {
+	var err error
+
+	switch r.TargetType {
+	case ReportTargetProblem:
+		err = d.QueryRow(`SELECT board_id FROM problems WHERE id = $1`, r.TargetID).Scan(&r.BoardID)
+	case ReportTargetComment:
+		err = d.QueryRow(`
+			SELECT p.board_id
+			FROM comments c
+			JOIN problems p ON p.id = c.problem_id
+			WHERE c.id = $1 AND c.deleted_at IS NULL
+		`, r.TargetID).Scan(&r.BoardID)
+	case ReportTargetBoard:
+		err = d.QueryRow(`SELECT id FROM boards WHERE id = $1`, r.TargetID).Scan(&r.BoardID)
+	}
+
+	if err == sql.ErrNoRows {
+		return ErrReportTargetNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying reported content: %v", err)
+	}
+
+	err = d.QueryRow(`
+		INSERT INTO reports (target_type, target_id, board_id, reporter_id, reason, details)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at
+	`, r.TargetType, r.TargetID, r.BoardID, r.ReporterID, r.Reason, r.Details).Scan(&r.ID, &r.Status, &r.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "unique_open_report" {
+			return ErrAlreadyReported
+		}
+
+		return fmt.Errorf("error creating report: %v", err)
+	}
+
+	return nil
+}
+
+const reportColumns = `
+	id, target_type, target_id, board_id, reporter_id, reason, details,
+	status, action, resolved_by, resolved_at, created_at`
+
+func scanReport(row rowScanner, r *Report) error {
+	return row.Scan( //nolint:wrapcheck
+		&r.ID, &r.TargetType, &r.TargetID, &r.BoardID, &r.ReporterID, &r.Reason, &r.Details,
+		&r.Status, &r.Action, &r.ResolvedBy, &r.ResolvedAt, &r.CreatedAt,
+	)
+}
+
+// GetReports returns one page of the reports with a status that a user can
+// moderate, oldest first. Site moderators see every report, and board owners
+// see reports about problems and comments on their boards.
+func (d *DB) GetReports(userID uuid.UUID, status ReportStatus, p Pagination) ([]Report, Metadata, error) {
+	rows, err := d.Query(`
+		SELECT COUNT(*) OVER (), `+reportColumns+`
+		FROM reports
+		WHERE status = $2 AND (
+			EXISTS (SELECT 1 FROM moderators WHERE user_id = $1)
+			OR (target_type <> 'board' AND board_id IN (SELECT id FROM boards WHERE owner_id = $1))
+		)
+		ORDER BY created_at, id
+		LIMIT $3 OFFSET $4
+	`, userID, status, p.limit(), p.offset())
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("error querying reports: %v", err)
+	}
+	defer rows.Close()
+
+	var (
+		totalRecords int
+		reports      = []Report{}
+	)
+
+	for rows.Next() {
+		var r Report
+
+		err := rows.Scan(
+			&totalRecords, &r.ID, &r.TargetType, &r.TargetID, &r.BoardID, &r.ReporterID, &r.Reason, &r.Details,
+			&r.Status, &r.Action, &r.ResolvedBy, &r.ResolvedAt, &r.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf("error scanning report: %v", err)
+		}
+
+		reports = append(reports, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, fmt.Errorf("error iterating reports: %v", err)
+	}
+
+	return reports, calculateMetadata(totalRecords, p), nil
+}
+
+// ResolveReport acts on a report, and on every other open report about the
+// same content. Hidden content stays in place but is left out of listings
+// for anyone who can't moderate it. Only site moderators can act on reports
+// about boards.
+func (d *DB) ResolveReport(reportID, userID uuid.UUID, action ModerationAction) (*Report, error) {
+	tx, err := d.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	var (
+		r                  Report
+		moderator, isOwner bool
+	)
+
+	err = tx.QueryRow(`
+		SELECT
+			EXISTS (SELECT 1 FROM moderators WHERE user_id = $2),
+			EXISTS (SELECT 1 FROM boards WHERE id = r.board_id AND owner_id = $2),
+			r.target_type, r.target_id, r.status
+		FROM reports r
+		WHERE r.id = $1
+		FOR UPDATE OF r
+	`, reportID, userID).Scan(&moderator, &isOwner, &r.TargetType, &r.TargetID, &r.Status)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrReportNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error querying report: %v", err)
+	}
+
+	if !moderator && (!isOwner || r.TargetType == ReportTargetBoard) {
+		return nil, ErrNotModerator
+	}
+
+	if r.Status != ReportStatusOpen {
+		return nil, ErrReportResolved
+	}
+
+	err = moderateContent(tx, r.TargetType, r.TargetID, userID, action)
+	if err != nil {
+		return nil, err
+	}
+
+	status := ReportStatusActioned
+	if action == ModerationActionDismiss {
+		status = ReportStatusDismissed
+	}
+
+	_, err = tx.Exec(`
+		UPDATE reports
+		SET status = $3, action = $4, resolved_by = $5, resolved_at = NOW()
+		WHERE target_type = $1 AND target_id = $2 AND status = 'open'
+	`, r.TargetType, r.TargetID, status, action, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving reports: %v", err)
+	}
+
+	err = scanReport(tx.QueryRow(`SELECT `+reportColumns+` FROM reports WHERE id = $1`, reportID), &r)
+	if err != nil {
+		return nil, fmt.Errorf("error querying report: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return &r, nil
+}
+
+// moderateContent hides or deletes reported content. Deleted comments keep
+// their place in the thread, like comments deleted by their author.
+func moderateContent(tx *sql.Tx, target ReportTarget, targetID, moderatorID uuid.UUID, action ModerationAction) error {
+	var err error
+
+	switch {
+	case action == ModerationActionDismiss:
+		return nil
+	case target == ReportTargetProblem && action == ModerationActionHide:
+		_, err = tx.Exec(`UPDATE problems SET hidden_at = NOW() WHERE id = $1`, targetID)
+	case target == ReportTargetProblem:
+		_, err = tx.Exec(`DELETE FROM problems WHERE id = $1`, targetID)
+	case target == ReportTargetComment && action == ModerationActionHide:
+		_, err = tx.Exec(`UPDATE comments SET hidden_at = NOW() WHERE id = $1`, targetID)
+	case target == ReportTargetComment:
+		_, err = tx.Exec(`
+			UPDATE comments
+			SET body = '', deleted_at = NOW(), deleted_by = $2
+			WHERE id = $1 AND deleted_at IS NULL
+		`, targetID, moderatorID)
+	case target == ReportTargetBoard && action == ModerationActionHide:
+		_, err = tx.Exec(`UPDATE boards SET hidden_at = NOW() WHERE id = $1`, targetID)
+	case target == ReportTargetBoard:
+		_, err = tx.Exec(`DELETE FROM boards WHERE id = $1`, targetID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error moderating %s: %v", target, err)
+	}
+
+	return nil
+}
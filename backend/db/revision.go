@@ -0,0 +1,262 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevisionHold is a hold as it was used by a problem at one revision.
+type RevisionHold struct {
+	HoldID uuid.UUID `json:"hold_id"`
+	Type   HoldType  `json:"type"`
+}
+
+// ProblemRevision is an immutable snapshot of a problem, written every time
+// the problem is created, edited or changes status. Numbers start at 1 and
+// increase by one with each change.
+type ProblemRevision struct {
+	ID        uuid.UUID      `json:"id"`
+	ProblemID uuid.UUID      `json:"problem_id"`
+	Number    int            `json:"number"`
+	Name      string         `json:"name"`
+	Status    ProblemStatus  `json:"status"`
+	Grade     *int           `json:"grade"`
+	Rules     ProblemRules   `json:"rules"`
+	Holds     []RevisionHold `json:"holds"`
+	EditorID  uuid.UUID      `json:"editor_id"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// FieldChange holds the old and new value of a field that differs between two
+// revisions.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+type RetypedHold struct {
+	HoldID uuid.UUID `json:"hold_id"`
+	From   HoldType  `json:"from"`
+	To     HoldType  `json:"to"`
+}
+
+// RevisionDiff describes how to get from one revision of a problem to another.
+type RevisionDiff struct {
+	From    int                    `json:"from"`
+	To      int                    `json:"to"`
+	Fields  map[string]FieldChange `json:"fields"`
+	Added   []RevisionHold         `json:"added"`
+	Removed []RevisionHold         `json:"removed"`
+	Retyped []RetypedHold          `json:"retyped"`
+}
+
+// DiffRevisions compares two revisions of the same problem. Holds are listed in
+// order of hold ID so the same pair of revisions always diffs the same way.
+func DiffRevisions(from, to ProblemRevision) RevisionDiff {
+	diff := RevisionDiff{
+		From:    from.Number,
+		To:      to.Number,
+		Fields:  make(map[string]FieldChange),
+		Added:   []RevisionHold{},
+		Removed: []RevisionHold{},
+		Retyped: []RetypedHold{},
+	}
+
+	if from.Name != to.Name {
+		diff.Fields["name"] = FieldChange{From: from.Name, To: to.Name}
+	}
+
+	if from.Status != to.Status {
+		diff.Fields["status"] = FieldChange{From: from.Status, To: to.Status}
+	}
+
+	if !equalGrades(from.Grade, to.Grade) {
+		diff.Fields["grade"] = FieldChange{From: from.Grade, To: to.Grade}
+	}
+
+	if from.Rules != to.Rules {
+		diff.Fields["rules"] = FieldChange{From: from.Rules, To: to.Rules}
+	}
+
+	before := make(map[uuid.UUID]HoldType, len(from.Holds))
+	for _, h := range from.Holds {
+		before[h.HoldID] = h.Type
+	}
+
+	after := make(map[uuid.UUID]HoldType, len(to.Holds))
+	for _, h := range to.Holds {
+		after[h.HoldID] = h.Type
+	}
+
+	for _, h := range to.Holds {
+		oldType, ok := before[h.HoldID]
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, h)
+		case oldType != h.Type:
+			diff.Retyped = append(diff.Retyped, RetypedHold{HoldID: h.HoldID, From: oldType, To: h.Type})
+		}
+	}
+
+	for _, h := range from.Holds {
+		if _, ok := after[h.HoldID]; !ok {
+			diff.Removed = append(diff.Removed, h)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].HoldID.String() < diff.Added[j].HoldID.String() })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].HoldID.String() < diff.Removed[j].HoldID.String() })
+	sort.Slice(diff.Retyped, func(i, j int) bool { return diff.Retyped[i].HoldID.String() < diff.Retyped[j].HoldID.String() })
+
+	return diff
+}
+
+func equalGrades(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// insertProblemRevision snapshots a problem and its holds as the next revision
+// within the transaction that changed them. The transaction must hold the
+// problem's row lock, which keeps revision numbers from being taken twice.
+func insertProblemRevision(tx *sql.Tx, problem *Problem, holds []ProblemHold, editorID uuid.UUID) error {
+	revisionHolds := make([]RevisionHold, 0, len(holds))
+	for _, h := range holds {
+		revisionHolds = append(revisionHolds, RevisionHold{HoldID: h.HoldID, Type: h.Type})
+	}
+
+	holdsJSON, err := json.Marshal(revisionHolds)
+	if err != nil {
+		return fmt.Errorf("error marshaling revision holds: %v", err)
+	}
+
+	rulesJSON, err := json.Marshal(problem.Rules)
+	if err != nil {
+		return fmt.Errorf("error marshaling revision rules: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO problem_revisions (problem_id, number, name, status, grade, rules, holds, editor_id)
+		SELECT $1, COALESCE(MAX(number), 0) + 1, $2, $3, $4, $5, $6, $7
+		FROM problem_revisions
+		WHERE problem_id = $1
+	`, problem.ID, problem.Name, problem.Status, problem.Grade, rulesJSON, holdsJSON, editorID)
+	if err != nil {
+		return fmt.Errorf("error creating problem revision: %v", err)
+	}
+
+	return nil
+}
+
+// currentRevisionHolds returns the holds a problem has now, as far as a
+// revision records them.
+func currentRevisionHolds(tx *sql.Tx, problemID uuid.UUID) ([]ProblemHold, error) {
+	rows, err := tx.Query(`SELECT hold_id, type FROM problem_holds WHERE problem_id = $1`, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying problem holds: %v", err)
+	}
+	defer rows.Close()
+
+	var holds []ProblemHold
+
+	for rows.Next() {
+		var h ProblemHold
+
+		err := rows.Scan(&h.HoldID, &h.Type)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning problem hold: %v", err)
+		}
+
+		holds = append(holds, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating problem holds: %v", err)
+	}
+
+	return holds, nil
+}
+
+const revisionColumns = `id, problem_id, number, name, status, grade, rules, holds, editor_id, created_at`
+
+func scanRevision(row rowScanner, r *ProblemRevision) error {
+	var rulesJSON, holdsJSON []byte
+
+	err := row.Scan(&r.ID, &r.ProblemID, &r.Number, &r.Name, &r.Status, &r.Grade, &rulesJSON, &holdsJSON, &r.EditorID, &r.CreatedAt)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	err = json.Unmarshal(rulesJSON, &r.Rules)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling revision rules: %v", err)
+	}
+
+	err = json.Unmarshal(holdsJSON, &r.Holds)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling revision holds: %v", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetProblemRevisions(problemID uuid.UUID) ([]ProblemRevision, error) {
+	rows, err := d.Query(`
+		SELECT `+revisionColumns+`
+		FROM problem_revisions
+		WHERE problem_id = $1
+		ORDER BY number
+	`, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying problem revisions: %v", err)
+	}
+	defer rows.Close()
+
+	var revisions []ProblemRevision
+
+	for rows.Next() {
+		var r ProblemRevision
+
+		err := scanRevision(rows, &r)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning problem revision: %v", err)
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating problem revisions: %v", err)
+	}
+
+	return revisions, nil
+}
+
+func (d *DB) GetProblemRevision(problemID uuid.UUID, number int) (*ProblemRevision, error) {
+	var r ProblemRevision
+
+	err := scanRevision(d.QueryRow(`
+		SELECT `+revisionColumns+`
+		FROM problem_revisions
+		WHERE problem_id = $1 AND number = $2
+	`, problemID, number), &r)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrRevisionNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error querying problem revision: %v", err)
+	}
+
+	return &r, nil
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	var (
+		hold1 = uuid.MustParse("00000000-0000-0000-0000-000000000201")
+		hold2 = uuid.MustParse("00000000-0000-0000-0000-000000000202")
+		hold3 = uuid.MustParse("00000000-0000-0000-0000-000000000203")
+		hold4 = uuid.MustParse("00000000-0000-0000-0000-000000000204")
+	)
+
+	grade := func(g int) *int { return &g }
+
+	base := ProblemRevision{
+		Number: 1,
+		Name:   "Crimpy",
+		Status: ProblemStatusDraft,
+		Grade:  grade(4),
+		Holds: []RevisionHold{
+			{HoldID: hold1, Type: HoldTypeStart},
+			{HoldID: hold2, Type: HoldTypeHand},
+		},
+	}
+
+	tests := []struct {
+		name string
+		to   func(r ProblemRevision) ProblemRevision
+		want RevisionDiff
+	}{
+		{
+			name: "no changes",
+			to:   func(r ProblemRevision) ProblemRevision { return r },
+			want: RevisionDiff{
+				Fields:  map[string]FieldChange{},
+				Added:   []RevisionHold{},
+				Removed: []RevisionHold{},
+				Retyped: []RetypedHold{},
+			},
+		},
+		{
+			name: "changed fields",
+			to: func(r ProblemRevision) ProblemRevision {
+				r.Name = "Slopey"
+				r.Status = ProblemStatusPublished
+				r.Grade = grade(5)
+				r.Rules.NoMatching = true
+
+				return r
+			},
+			want: RevisionDiff{
+				Fields: map[string]FieldChange{
+					"name":   {From: "Crimpy", To: "Slopey"},
+					"status": {From: ProblemStatusDraft, To: ProblemStatusPublished},
+					"grade":  {From: grade(4), To: grade(5)},
+					"rules":  {From: ProblemRules{}, To: ProblemRules{NoMatching: true}},
+				},
+				Added:   []RevisionHold{},
+				Removed: []RevisionHold{},
+				Retyped: []RetypedHold{},
+			},
+		},
+		{
+			name: "equal grades at different addresses",
+			to: func(r ProblemRevision) ProblemRevision {
+				r.Grade = grade(4)
+				return r
+			},
+			want: RevisionDiff{
+				Fields:  map[string]FieldChange{},
+				Added:   []RevisionHold{},
+				Removed: []RevisionHold{},
+				Retyped: []RetypedHold{},
+			},
+		},
+		{
+			name: "grade removed",
+			to: func(r ProblemRevision) ProblemRevision {
+				r.Grade = nil
+				return r
+			},
+			want: RevisionDiff{
+				Fields:  map[string]FieldChange{"grade": {From: grade(4), To: (*int)(nil)}},
+				Added:   []RevisionHold{},
+				Removed: []RevisionHold{},
+				Retyped: []RetypedHold{},
+			},
+		},
+		{
+			name: "holds added, removed and retyped",
+			to: func(r ProblemRevision) ProblemRevision {
+				r.Holds = []RevisionHold{
+					{HoldID: hold3, Type: HoldTypeFinish},
+					{HoldID: hold2, Type: HoldTypeFoot},
+				}
+
+				return r
+			},
+			want: RevisionDiff{
+				Fields:  map[string]FieldChange{},
+				Added:   []RevisionHold{{HoldID: hold3, Type: HoldTypeFinish}},
+				Removed: []RevisionHold{{HoldID: hold1, Type: HoldTypeStart}},
+				Retyped: []RetypedHold{{HoldID: hold2, From: HoldTypeHand, To: HoldTypeFoot}},
+			},
+		},
+		{
+			name: "holds listed in hold ID order",
+			to: func(r ProblemRevision) ProblemRevision {
+				r.Holds = []RevisionHold{
+					{HoldID: hold4, Type: HoldTypeFoot},
+					{HoldID: hold3, Type: HoldTypeFoot},
+					{HoldID: hold2, Type: HoldTypeHand},
+					{HoldID: hold1, Type: HoldTypeStart},
+				}
+
+				return r
+			},
+			want: RevisionDiff{
+				Fields:  map[string]FieldChange{},
+				Added:   []RevisionHold{{HoldID: hold3, Type: HoldTypeFoot}, {HoldID: hold4, Type: HoldTypeFoot}},
+				Removed: []RevisionHold{},
+				Retyped: []RetypedHold{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := tt.to(base)
+			to.Number = 2
+
+			tt.want.From, tt.want.To = 1, 2
+
+			got := DiffRevisions(base, to)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SessionGap is the longest rest between attempts that still counts as one
+// session when the user hasn't started a session themselves.
+const SessionGap = 2 * time.Hour
+
+// Session is a visit to the wall. Users start and end sessions themselves, or
+// attempts logged outside one are grouped into automatic sessions, which end
+// at their latest attempt.
+type Session struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Auto      bool       `json:"auto"`
+}
+
+// SessionSend is the hardest problem sent in a session.
+type SessionSend struct {
+	ProblemID uuid.UUID `json:"problem_id"`
+	BoardID   uuid.UUID `json:"board_id"`
+	Name      string    `json:"name"`
+	Grade     int       `json:"grade"`
+}
+
+type SessionSummary struct {
+	Session
+	DurationSeconds int          `json:"duration_seconds"`
+	Attempts        int          `json:"attempts"`
+	ProblemsTried   int          `json:"problems_tried"`
+	Sends           int          `json:"sends"`
+	HardestSend     *SessionSend `json:"hardest_send"`
+}
+
+// currentSession returns the session a new attempt by the user belongs to:
+// their open session if they have one, otherwise their latest automatic
+// session if it ended within SessionGap, otherwise a new automatic session.
+func currentSession(tx *sql.Tx, userID uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := tx.QueryRow(`
+		SELECT id
+		FROM sessions
+		WHERE user_id = $1 AND ended_at IS NULL
+	`, userID).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("error querying open session: %v", err)
+	}
+
+	err = tx.QueryRow(`
+		UPDATE sessions
+		SET ended_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM sessions
+			WHERE user_id = $1 AND auto AND ended_at >= NOW() - make_interval(secs => $2)
+			ORDER BY ended_at DESC
+			LIMIT 1
+		)
+		RETURNING id
+	`, userID, SessionGap.Seconds()).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("error extending session: %v", err)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO sessions (user_id, ended_at, auto)
+		VALUES ($1, NOW(), TRUE)
+		RETURNING id
+	`, userID).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("error creating session: %v", err)
+	}
+
+	return id, nil
+}
+
+// StartSession opens a session that the user's attempts are logged in until
+// they end it.
+func (d *DB) StartSession(userID uuid.UUID) (*Session, error) {
+	s := Session{UserID: userID}
+
+	err := d.QueryRow(`
+		INSERT INTO sessions (user_id)
+		VALUES ($1)
+		RETURNING id, started_at
+	`, userID).Scan(&s.ID, &s.StartedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "unique_open_session" {
+			return nil, ErrSessionOpen
+		}
+
+		return nil, fmt.Errorf("error starting session: %v", err)
+	}
+
+	return &s, nil
+}
+
+func (d *DB) EndSession(sessionID, userID uuid.UUID) (*Session, error) {
+	tx, err := d.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	s := Session{ID: sessionID}
+
+	err = tx.QueryRow(`
+		SELECT user_id, started_at, ended_at, auto
+		FROM sessions
+		WHERE id = $1
+		FOR UPDATE
+	`, sessionID).Scan(&s.UserID, &s.StartedAt, &s.EndedAt, &s.Auto)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error querying session: %v", err)
+	}
+
+	if s.UserID != userID {
+		return nil, ErrNotSessionOwner
+	}
+
+	if s.EndedAt != nil {
+		return nil, ErrSessionEnded
+	}
+
+	err = tx.QueryRow(`
+		UPDATE sessions
+		SET ended_at = NOW()
+		WHERE id = $1
+		RETURNING ended_at
+	`, sessionID).Scan(&s.EndedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error ending session: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return &s, nil
+}
+
+func scanSessionSummary(r rowScanner, s *SessionSummary) error {
+	var (
+		problemID, boardID *uuid.UUID
+		name               *string
+		grade              *int
+	)
+
+	err := r.Scan(
+		&s.ID, &s.UserID, &s.StartedAt, &s.EndedAt, &s.Auto,
+		&s.DurationSeconds, &s.Attempts, &s.ProblemsTried, &s.Sends,
+		&problemID, &boardID, &name, &grade,
+	)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if problemID != nil {
+		s.HardestSend = &SessionSend{ProblemID: *problemID, BoardID: *boardID, Name: *name, Grade: *grade}
+	}
+
+	return nil
+}
+
+// GetSessionSummary summarises a session as viewerID can see it.
+func (d *DB) GetSessionSummary(sessionID, viewerID uuid.UUID) (*SessionSummary, error) {
+	sessions, err := d.getSessionSummaries(&sessionID, nil, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sessions) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	return &sessions[0], nil
+}
+
+// GetSessionSummaries returns a user's sessions as viewerID can see them,
+// newest first.
+func (d *DB) GetSessionSummaries(userID, viewerID uuid.UUID) ([]SessionSummary, error) {
+	return d.getSessionSummaries(nil, &userID, viewerID)
+}
+
+// getSessionSummaries summarises one session or all of a user's sessions.
+// Open sessions last until now. Attempts on boards viewerID can't see are
+// left out, so a private board's problems aren't given away.
+func (d *DB) getSessionSummaries(sessionID, userID *uuid.UUID, viewerID uuid.UUID) ([]SessionSummary, error) {
+	rows, err := d.Query(`
+		SELECT
+			s.id, s.user_id, s.started_at, s.ended_at, s.auto,
+			EXTRACT(EPOCH FROM COALESCE(s.ended_at, NOW()) - s.started_at)::int,
+			COUNT(a.id),
+			COUNT(DISTINCT a.problem_id),
+			COUNT(DISTINCT a.problem_id) FILTER (WHERE a.status = 'sent'),
+			h.id, h.board_id, h.name, h.grade
+		FROM sessions s
+		LEFT JOIN (
+			attempts a
+			JOIN problems ap ON ap.id = a.problem_id AND board_visible_to(ap.board_id, $3)
+		) ON a.session_id = s.id
+		LEFT JOIN LATERAL (
+			SELECT p.id, p.board_id, p.name, p.grade
+			FROM attempts sa
+			JOIN problems p ON p.id = sa.problem_id
+			WHERE sa.session_id = s.id AND sa.status = 'sent' AND p.grade IS NOT NULL
+				AND board_visible_to(p.board_id, $3)
+			ORDER BY p.grade DESC, sa.attempted_at
+			LIMIT 1
+		) h ON TRUE
+		WHERE ($1::uuid IS NULL OR s.id = $1) AND ($2::uuid IS NULL OR s.user_id = $2)
+		GROUP BY s.id, h.id, h.board_id, h.name, h.grade
+		ORDER BY s.started_at DESC
+	`, sessionID, userID, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying sessions: %v", err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionSummary
+
+	for rows.Next() {
+		var s SessionSummary
+
+		err := scanSessionSummary(rows, &s)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning session: %v", err)
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %v", err)
+	}
+
+	return sessions, nil
+}
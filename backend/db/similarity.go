@@ -0,0 +1,131 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// NearDuplicateSimilarity is the similarity at or above which two problems are
+// treated as probably being the same problem set twice.
+const NearDuplicateSimilarity = 0.8
+
+type SimilarProblem struct {
+	Problem    Problem `json:"problem"`
+	Similarity float64 `json:"similarity"`
+	Exact      bool    `json:"exact"`
+}
+
+// HoldSetSimilarity compares two sets of problem holds. It averages the
+// Jaccard index of the hold IDs with the Jaccard index of the (hold, type)
+// pairs, so using the same holds scores highly even when some are typed
+// differently, and only identical problems score 1.
+func HoldSetSimilarity(a, b []ProblemHold) float64 {
+	type typedHold struct {
+		id uuid.UUID
+		t  HoldType
+	}
+
+	ids := func(holds []ProblemHold) map[uuid.UUID]bool {
+		set := make(map[uuid.UUID]bool, len(holds))
+		for _, h := range holds {
+			set[h.HoldID] = true
+		}
+
+		return set
+	}
+
+	typed := func(holds []ProblemHold) map[typedHold]bool {
+		set := make(map[typedHold]bool, len(holds))
+		for _, h := range holds {
+			set[typedHold{h.HoldID, h.Type}] = true
+		}
+
+		return set
+	}
+
+	return (jaccard(ids(a), ids(b)) + jaccard(typed(a), typed(b))) / 2
+}
+
+func jaccard[T comparable](a, b map[T]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+
+	intersection := 0
+
+	for k := range a {
+		if b[k] {
+			intersection++
+		}
+	}
+
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// FindSimilarProblems ranks the other problems on a board by how similar their
+// holds are to holds, most similar first. Problems below minSimilarity are
+// left out, as are problemID itself and problems a moderator has hidden.
+func (d *DB) FindSimilarProblems(boardID, problemID uuid.UUID, holds []ProblemHold, minSimilarity float64, limit int) ([]SimilarProblem, error) {
+	problems, err := d.GetProblems(boardID, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.Query(`
+		SELECT ph.problem_id, ph.hold_id, ph.type
+		FROM problem_holds ph
+		JOIN problems p ON p.id = ph.problem_id
+		WHERE p.board_id = $1 AND p.id <> $2
+	`, boardID, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying problem holds: %v", err)
+	}
+	defer rows.Close()
+
+	holdSets := make(map[uuid.UUID][]ProblemHold)
+
+	for rows.Next() {
+		var h ProblemHold
+
+		err := rows.Scan(&h.ProblemID, &h.HoldID, &h.Type)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning problem hold: %v", err)
+		}
+
+		holdSets[h.ProblemID] = append(holdSets[h.ProblemID], h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating problem holds: %v", err)
+	}
+
+	var similar []SimilarProblem
+
+	for _, p := range problems {
+		other, ok := holdSets[p.ID]
+		if !ok {
+			continue
+		}
+
+		similarity := HoldSetSimilarity(holds, other)
+		if similarity == 0 || similarity < minSimilarity {
+			continue
+		}
+
+		similar = append(similar, SimilarProblem{
+			Problem:    p,
+			Similarity: similarity,
+			Exact:      similarity == 1,
+		})
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool { return similar[i].Similarity > similar[j].Similarity })
+
+	if limit > 0 && len(similar) > limit {
+		similar = similar[:limit]
+	}
+
+	return similar, nil
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHoldSetSimilarity(t *testing.T) {
+	var (
+		hold1 = uuid.MustParse("00000000-0000-0000-0000-000000000401")
+		hold2 = uuid.MustParse("00000000-0000-0000-0000-000000000402")
+		hold3 = uuid.MustParse("00000000-0000-0000-0000-000000000403")
+	)
+
+	problem := []ProblemHold{
+		{HoldID: hold1, Type: HoldTypeStart},
+		{HoldID: hold2, Type: HoldTypeFinish},
+	}
+
+	tests := []struct {
+		name string
+		a, b []ProblemHold
+		want float64
+	}{
+		{
+			name: "identical",
+			a:    problem,
+			b:    problem,
+			want: 1,
+		},
+		{
+			name: "same holds typed differently",
+			a:    problem,
+			b:    []ProblemHold{{HoldID: hold1, Type: HoldTypeStart}, {HoldID: hold2, Type: HoldTypeHand}},
+			// ids 2/2, typed 1/3
+			want: (1 + 1.0/3) / 2,
+		},
+		{
+			name: "one hold added",
+			a:    problem,
+			b:    append([]ProblemHold{{HoldID: hold3, Type: HoldTypeFoot}}, problem...),
+			want: 2.0 / 3,
+		},
+		{
+			name: "nothing in common",
+			a:    problem,
+			b:    []ProblemHold{{HoldID: hold3, Type: HoldTypeStart}},
+			want: 0,
+		},
+		{
+			name: "both empty",
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HoldSetSimilarity(tt.a, tt.b)
+
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %f, want %f", got, tt.want)
+			}
+
+			if reverse := HoldSetSimilarity(tt.b, tt.a); math.Abs(reverse-got) > 1e-9 {
+				t.Errorf("not symmetric: got %f one way and %f the other", got, reverse)
+			}
+		})
+	}
+}
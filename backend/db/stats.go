@@ -0,0 +1,241 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatsAttempt is one attempt from a user's log, with the grade of its problem.
+type StatsAttempt struct {
+	ProblemID   uuid.UUID
+	SessionID   *uuid.UUID
+	Grade       *int
+	Status      string
+	AttemptedAt time.Time
+}
+
+// GradeStats covers one grade of the send pyramid. A flash is a send on the
+// first attempt, and FlashRate is the share of problems tried that were
+// flashed.
+type GradeStats struct {
+	Grade     int     `json:"grade"`
+	Tried     int     `json:"tried"`
+	Sends     int     `json:"sends"`
+	Flashes   int     `json:"flashes"`
+	FlashRate float64 `json:"flash_rate"`
+}
+
+// WeekStats covers the week starting on Monday at WeekStart, in UTC.
+type WeekStats struct {
+	WeekStart   time.Time `json:"week_start"`
+	Sessions    int       `json:"sessions"`
+	Attempts    int       `json:"attempts"`
+	Sends       int       `json:"sends"`
+	HardestSend *int      `json:"hardest_send"`
+}
+
+// UserStats summarises a user's attempt log. Weeks runs from the week of their
+// first attempt to the current week, including weeks they didn't climb.
+// Streaks count consecutive weeks with at least one session, and the current
+// streak survives until a whole week is missed.
+type UserStats struct {
+	UserID          uuid.UUID    `json:"user_id"`
+	Attempts        int          `json:"attempts"`
+	Sends           int          `json:"sends"`
+	Sessions        int          `json:"sessions"`
+	HardestSend     *int         `json:"hardest_send"`
+	Pyramid         []GradeStats `json:"pyramid"`
+	Weeks           []WeekStats  `json:"weeks"`
+	SessionsPerWeek float64      `json:"sessions_per_week"`
+	CurrentStreak   int          `json:"current_streak"`
+	LongestStreak   int          `json:"longest_streak"`
+}
+
+// weekStart returns midnight UTC on the Monday of t's week.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// ComputeUserStats builds stats from a user's attempts, which must be oldest
+// first. Sends count each problem once, in the week it was first sent.
+func ComputeUserStats(userID uuid.UUID, attempts []StatsAttempt, now time.Time) UserStats {
+	stats := UserStats{
+		UserID:   userID,
+		Attempts: len(attempts),
+		Pyramid:  []GradeStats{},
+		Weeks:    []WeekStats{},
+	}
+
+	if len(attempts) == 0 {
+		return stats
+	}
+
+	type problemLog struct {
+		grade    *int
+		attempts int
+		sent     bool
+		flashed  bool
+	}
+
+	problems := make(map[uuid.UUID]*problemLog)
+	sessionWeeks := make(map[uuid.UUID]time.Time)
+
+	first := weekStart(attempts[0].AttemptedAt)
+	current := weekStart(now)
+
+	// Clocks disagree, so attempts can be logged for a week that hasn't
+	// started yet here
+	if last := weekStart(attempts[len(attempts)-1].AttemptedAt); current.Before(last) {
+		current = last
+	}
+
+	weekCount := int(current.Sub(first).Hours()/(24*7)) + 1
+	stats.Weeks = make([]WeekStats, weekCount)
+
+	for i := range stats.Weeks {
+		stats.Weeks[i].WeekStart = first.AddDate(0, 0, 7*i)
+	}
+
+	for _, a := range attempts {
+		start := weekStart(a.AttemptedAt)
+		week := &stats.Weeks[int(start.Sub(first).Hours()/(24*7))]
+		week.Attempts++
+
+		if a.SessionID != nil {
+			if _, ok := sessionWeeks[*a.SessionID]; !ok {
+				sessionWeeks[*a.SessionID] = start
+				week.Sessions++
+			}
+		}
+
+		p, ok := problems[a.ProblemID]
+		if !ok {
+			p = &problemLog{grade: a.Grade}
+			problems[a.ProblemID] = p
+		}
+
+		p.attempts++
+
+		if a.Status != AttemptStatusSent || p.sent {
+			continue
+		}
+
+		p.sent = true
+		p.flashed = p.attempts == 1
+		week.Sends++
+		stats.Sends++
+
+		if a.Grade == nil {
+			continue
+		}
+
+		if week.HardestSend == nil || *a.Grade > *week.HardestSend {
+			week.HardestSend = a.Grade
+		}
+
+		if stats.HardestSend == nil || *a.Grade > *stats.HardestSend {
+			stats.HardestSend = a.Grade
+		}
+	}
+
+	grades := make(map[int]*GradeStats)
+
+	for _, p := range problems {
+		if p.grade == nil {
+			continue
+		}
+
+		g, ok := grades[*p.grade]
+		if !ok {
+			g = &GradeStats{Grade: *p.grade}
+			grades[*p.grade] = g
+		}
+
+		g.Tried++
+
+		if p.sent {
+			g.Sends++
+		}
+
+		if p.flashed {
+			g.Flashes++
+		}
+	}
+
+	for _, g := range grades {
+		g.FlashRate = float64(g.Flashes) / float64(g.Tried)
+		stats.Pyramid = append(stats.Pyramid, *g)
+	}
+
+	sort.Slice(stats.Pyramid, func(i, j int) bool { return stats.Pyramid[i].Grade > stats.Pyramid[j].Grade })
+
+	stats.Sessions = len(sessionWeeks)
+	stats.SessionsPerWeek = float64(stats.Sessions) / float64(weekCount)
+
+	streak := 0
+
+	for _, w := range stats.Weeks {
+		if w.Sessions == 0 {
+			streak = 0
+			continue
+		}
+
+		streak++
+		stats.LongestStreak = max(stats.LongestStreak, streak)
+	}
+
+	// A week without climbing yet doesn't break the streak until it's over
+	last := len(stats.Weeks) - 1
+	if stats.Weeks[last].Sessions == 0 && last > 0 {
+		streak = 0
+
+		for i := last - 1; i >= 0 && stats.Weeks[i].Sessions > 0; i-- {
+			streak++
+		}
+	}
+
+	stats.CurrentStreak = streak
+
+	return stats
+}
+
+// GetStatsAttempts returns every attempt a user has logged on boards viewerID
+// can see, oldest first.
+func (d *DB) GetStatsAttempts(userID, viewerID uuid.UUID) ([]StatsAttempt, error) {
+	rows, err := d.Query(`
+		SELECT a.problem_id, a.session_id, p.grade, a.status, a.attempted_at
+		FROM attempts a
+		JOIN problems p ON p.id = a.problem_id
+		WHERE a.user_id = $1 AND board_visible_to(p.board_id, $2)
+		ORDER BY a.attempted_at, a.id
+	`, userID, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attempts: %v", err)
+	}
+	defer rows.Close()
+
+	var attempts []StatsAttempt
+
+	for rows.Next() {
+		var a StatsAttempt
+
+		err := rows.Scan(&a.ProblemID, &a.SessionID, &a.Grade, &a.Status, &a.AttemptedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning attempt: %v", err)
+		}
+
+		attempts = append(attempts, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attempts: %v", err)
+	}
+
+	return attempts, nil
+}
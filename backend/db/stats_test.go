@@ -0,0 +1,210 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func statsAttempt(problemID uuid.UUID, sessionID *uuid.UUID, status, at string) StatsAttempt {
+	attemptedAt, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		panic(err)
+	}
+
+	return StatsAttempt{ProblemID: problemID, SessionID: sessionID, Status: status, AttemptedAt: attemptedAt}
+}
+
+func TestComputeUserStats(t *testing.T) {
+	var (
+		userID   = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+		problem1 = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+		problem2 = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+		session1 = uuid.MustParse("00000000-0000-0000-0000-000000000101")
+		session2 = uuid.MustParse("00000000-0000-0000-0000-000000000102")
+		session3 = uuid.MustParse("00000000-0000-0000-0000-000000000103")
+	)
+
+	type week struct {
+		start    string
+		sessions int
+		attempts int
+		sends    int
+	}
+
+	tests := []struct {
+		name         string
+		attempts     []StatsAttempt
+		now          string
+		wantWeeks    []week
+		wantSends    int
+		wantSessions int
+		wantCurrent  int
+		wantLongest  int
+	}{
+		{
+			name:      "no attempts",
+			now:       "2024-01-10T12:00:00Z",
+			wantWeeks: []week{},
+		},
+		{
+			name: "weeks start on monday in utc",
+			attempts: []StatsAttempt{
+				statsAttempt(problem1, &session1, AttemptStatusFailed, "2024-01-07T23:00:00Z"),
+				statsAttempt(problem1, &session1, AttemptStatusFailed, "2024-01-08T01:00:00+02:00"),
+				statsAttempt(problem1, &session2, AttemptStatusSent, "2024-01-08T00:00:00Z"),
+			},
+			now: "2024-01-09T12:00:00Z",
+			wantWeeks: []week{
+				{start: "2024-01-01", sessions: 1, attempts: 2},
+				{start: "2024-01-08", sessions: 1, attempts: 1, sends: 1},
+			},
+			wantSends:    1,
+			wantSessions: 2,
+			wantCurrent:  2,
+			wantLongest:  2,
+		},
+		{
+			name: "weeks without climbing are included",
+			attempts: []StatsAttempt{
+				statsAttempt(problem1, &session1, AttemptStatusSent, "2024-01-02T18:00:00Z"),
+				statsAttempt(problem2, &session2, AttemptStatusSent, "2024-01-17T18:00:00Z"),
+			},
+			now: "2024-01-17T20:00:00Z",
+			wantWeeks: []week{
+				{start: "2024-01-01", sessions: 1, attempts: 1, sends: 1},
+				{start: "2024-01-08"},
+				{start: "2024-01-15", sessions: 1, attempts: 1, sends: 1},
+			},
+			wantSends:    2,
+			wantSessions: 2,
+			wantCurrent:  1,
+			wantLongest:  1,
+		},
+		{
+			name: "attempts in a week that hasn't started yet",
+			attempts: []StatsAttempt{
+				statsAttempt(problem1, &session1, AttemptStatusFailed, "2024-01-02T18:00:00Z"),
+				statsAttempt(problem1, &session2, AttemptStatusSent, "2024-01-15T08:00:00Z"),
+			},
+			now: "2024-01-14T23:00:00Z",
+			wantWeeks: []week{
+				{start: "2024-01-01", sessions: 1, attempts: 1},
+				{start: "2024-01-08"},
+				{start: "2024-01-15", sessions: 1, attempts: 1, sends: 1},
+			},
+			wantSends:    1,
+			wantSessions: 2,
+			wantCurrent:  1,
+			wantLongest:  1,
+		},
+		{
+			name: "sends count once in the week first sent",
+			attempts: []StatsAttempt{
+				statsAttempt(problem1, &session1, AttemptStatusSent, "2024-01-02T18:00:00Z"),
+				statsAttempt(problem1, &session2, AttemptStatusSent, "2024-01-09T18:00:00Z"),
+			},
+			now: "2024-01-09T20:00:00Z",
+			wantWeeks: []week{
+				{start: "2024-01-01", sessions: 1, attempts: 1, sends: 1},
+				{start: "2024-01-08", sessions: 1, attempts: 1},
+			},
+			wantSends:    1,
+			wantSessions: 2,
+			wantCurrent:  2,
+			wantLongest:  2,
+		},
+		{
+			name: "streak survives the current week until it's over",
+			attempts: []StatsAttempt{
+				statsAttempt(problem1, &session1, AttemptStatusFailed, "2024-01-02T18:00:00Z"),
+				statsAttempt(problem1, &session2, AttemptStatusFailed, "2024-01-09T18:00:00Z"),
+			},
+			now: "2024-01-21T23:59:00Z",
+			wantWeeks: []week{
+				{start: "2024-01-01", sessions: 1, attempts: 1},
+				{start: "2024-01-08", sessions: 1, attempts: 1},
+				{start: "2024-01-15"},
+			},
+			wantSessions: 2,
+			wantCurrent:  2,
+			wantLongest:  2,
+		},
+		{
+			name: "streak ends after a whole week is missed",
+			attempts: []StatsAttempt{
+				statsAttempt(problem1, &session1, AttemptStatusFailed, "2024-01-02T18:00:00Z"),
+				statsAttempt(problem1, &session2, AttemptStatusFailed, "2024-01-09T18:00:00Z"),
+			},
+			now: "2024-01-22T00:00:00Z",
+			wantWeeks: []week{
+				{start: "2024-01-01", sessions: 1, attempts: 1},
+				{start: "2024-01-08", sessions: 1, attempts: 1},
+				{start: "2024-01-15"},
+				{start: "2024-01-22"},
+			},
+			wantSessions: 2,
+			wantCurrent:  0,
+			wantLongest:  2,
+		},
+		{
+			name: "longest streak outlasts the current one",
+			attempts: []StatsAttempt{
+				statsAttempt(problem1, &session1, AttemptStatusFailed, "2024-01-02T18:00:00Z"),
+				statsAttempt(problem1, &session2, AttemptStatusFailed, "2024-01-09T18:00:00Z"),
+				statsAttempt(problem1, &session3, AttemptStatusFailed, "2024-01-23T18:00:00Z"),
+				statsAttempt(problem2, nil, AttemptStatusFailed, "2024-01-24T18:00:00Z"),
+			},
+			now: "2024-01-25T12:00:00Z",
+			wantWeeks: []week{
+				{start: "2024-01-01", sessions: 1, attempts: 1},
+				{start: "2024-01-08", sessions: 1, attempts: 1},
+				{start: "2024-01-15"},
+				{start: "2024-01-22", sessions: 1, attempts: 2},
+			},
+			wantSessions: 3,
+			wantCurrent:  1,
+			wantLongest:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stats := ComputeUserStats(userID, tt.attempts, now)
+
+			if len(stats.Weeks) != len(tt.wantWeeks) {
+				t.Fatalf("got %d weeks, want %d", len(stats.Weeks), len(tt.wantWeeks))
+			}
+
+			for i, w := range stats.Weeks {
+				got := week{start: w.WeekStart.Format(time.DateOnly), sessions: w.Sessions, attempts: w.Attempts, sends: w.Sends}
+
+				if got != tt.wantWeeks[i] {
+					t.Errorf("week %d: got %+v, want %+v", i, got, tt.wantWeeks[i])
+				}
+			}
+
+			if stats.Sends != tt.wantSends {
+				t.Errorf("sends: got %d, want %d", stats.Sends, tt.wantSends)
+			}
+
+			if stats.Sessions != tt.wantSessions {
+				t.Errorf("sessions: got %d, want %d", stats.Sessions, tt.wantSessions)
+			}
+
+			if stats.CurrentStreak != tt.wantCurrent {
+				t.Errorf("current streak: got %d, want %d", stats.CurrentStreak, tt.wantCurrent)
+			}
+
+			if stats.LongestStreak != tt.wantLongest {
+				t.Errorf("longest streak: got %d, want %d", stats.LongestStreak, tt.wantLongest)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vizvim/bloc/backend/events"
+)
+
+type ProblemAction string
+
+const (
+	ProblemActionSubmit  ProblemAction = "submit"
+	ProblemActionApprove ProblemAction = "approve"
+	ProblemActionReject  ProblemAction = "reject"
+	ProblemActionArchive ProblemAction = "archive"
+)
+
+// problemLifecycle lists the status each action moves a problem from and to.
+// Any other change of status is not allowed.
+var problemLifecycle = map[ProblemAction]struct {
+	from, to ProblemStatus
+}{
+	ProblemActionSubmit:  {ProblemStatusDraft, ProblemStatusInReview},
+	ProblemActionApprove: {ProblemStatusInReview, ProblemStatusPublished},
+	ProblemActionReject:  {ProblemStatusInReview, ProblemStatusDraft},
+	ProblemActionArchive: {ProblemStatusPublished, ProblemStatusArchived},
+}
+
+// ProblemTransition records one step of a problem through its lifecycle, and
+// the reviewer's comment when it was approved or rejected.
+type ProblemTransition struct {
+	ID         uuid.UUID     `json:"id"`
+	ProblemID  uuid.UUID     `json:"problem_id"`
+	Action     ProblemAction `json:"action"`
+	FromStatus ProblemStatus `json:"from_status"`
+	ToStatus   ProblemStatus `json:"to_status"`
+	ActorID    uuid.UUID     `json:"actor_id"`
+	Comment    string        `json:"comment"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// TransitionProblem applies t.Action to a problem on behalf of t.ActorID and
+// records its new status as a revision. Submitting and archiving are done by
+// the problem's setter, while approving and rejecting must be done by someone
+// else.
+func (d *DB) TransitionProblem(boardID uuid.UUID, t *ProblemTransition) error {
+	step, ok := problemLifecycle[t.Action]
+	if !ok {
+		return ErrInvalidTransition
+	}
+
+	tx, err := d.Begin()
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %v", err)
+	}
+
+	defer tx.Rollback() //nolint:errcheck
+
+	var (
+		status   ProblemStatus
+		setterID uuid.UUID
+	)
+
+	err = tx.QueryRow(`
+		SELECT status, setter_id
+		FROM problems
+		WHERE id = $1 AND board_id = $2
+		FOR UPDATE
+	`, t.ProblemID, boardID).Scan(&status, &setterID)
+
+	if err == sql.ErrNoRows {
+		return ErrProblemNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("error querying problem: %v", err)
+	}
+
+	if status != step.from {
+		return ErrInvalidTransition
+	}
+
+	switch t.Action {
+	case ProblemActionSubmit, ProblemActionArchive:
+		if t.ActorID != setterID {
+			return ErrNotProblemSetter
+		}
+	case ProblemActionApprove, ProblemActionReject:
+		if t.ActorID == setterID {
+			return ErrSelfReview
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE problems SET status = $1 WHERE id = $2`, step.to, t.ProblemID)
+	if err != nil {
+		return fmt.Errorf("error updating problem status: %v", err)
+	}
+
+	t.FromStatus = step.from
+	t.ToStatus = step.to
+
+	err = tx.QueryRow(`
+		INSERT INTO problem_transitions (problem_id, action, from_status, to_status, actor_id, comment)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, t.ProblemID, t.Action, t.FromStatus, t.ToStatus, t.ActorID, t.Comment).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording problem transition: %v", err)
+	}
+
+	eventType := events.ProblemUpdated
+	if t.Action == ProblemActionApprove {
+		eventType = events.ProblemPublished
+	}
+
+	var problem Problem
+
+	err = scanProblem(tx.QueryRow(`SELECT `+problemColumns+` FROM problems WHERE id = $1`, t.ProblemID), &problem)
+	if err != nil {
+		return fmt.Errorf("error querying problem: %v", err)
+	}
+
+	// Every change of status is a new revision, so the history shows when a
+	// problem was submitted, reviewed and archived
+	holds, err := currentRevisionHolds(tx, t.ProblemID)
+	if err != nil {
+		return err
+	}
+
+	err = insertProblemRevision(tx, &problem, holds, t.ActorID)
+	if err != nil {
+		return err
+	}
+
+	err = enqueueWebhooks(tx, events.Event{Type: eventType, BoardID: boardID, Data: problem}, problem.Grade)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (d *DB) GetProblemTransitions(problemID uuid.UUID) ([]ProblemTransition, error) {
+	rows, err := d.Query(`
+		SELECT id, problem_id, action, from_status, to_status, actor_id, comment, created_at
+		FROM problem_transitions
+		WHERE problem_id = $1
+		ORDER BY created_at
+	`, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying problem transitions: %v", err)
+	}
+	defer rows.Close()
+
+	var transitions []ProblemTransition
+
+	for rows.Next() {
+		var t ProblemTransition
+
+		err := rows.Scan(&t.ID, &t.ProblemID, &t.Action, &t.FromStatus, &t.ToStatus, &t.ActorID, &t.Comment, &t.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning problem transition: %v", err)
+		}
+
+		transitions = append(transitions, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating problem transitions: %v", err)
+	}
+
+	return transitions, nil
+}
@@ -0,0 +1,379 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vizvim/bloc/backend/events"
+	"github.com/vizvim/bloc/backend/validator"
+)
+
+// WebhookEvents are the events a webhook can subscribe to.
+var WebhookEvents = []events.Type{
+	events.ProblemCreated,
+	events.ProblemPublished,
+	events.ProblemUpdated,
+	events.HoldLayoutChanged,
+	events.AttemptLogged,
+	events.ProblemSent,
+	events.CommentCreated,
+}
+
+// Webhook posts a board's events to a URL. When MinGrade is set, only events
+// about a problem graded at least that hard are sent. Secret signs each
+// delivery and is only returned when the webhook is created.
+type Webhook struct {
+	ID        uuid.UUID     `json:"id"`
+	BoardID   uuid.UUID     `json:"board_id"`
+	URL       string        `json:"url"`
+	Secret    string        `json:"secret,omitempty"`
+	Events    []events.Type `json:"events"`
+	MinGrade  *int          `json:"min_grade"`
+	CreatedBy uuid.UUID     `json:"created_by"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+func (w Webhook) Validate() map[string]string {
+	v := validator.New()
+
+	u, err := url.Parse(w.URL)
+	v.Check(w.URL != "", "url", "must be provided")
+	v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "url", "must be an absolute http or https URL")
+
+	if err == nil {
+		v.Check(publicWebhookHost(u.Hostname()), "url", "must not point at a loopback, private or link-local address")
+	}
+
+	v.Check(len(w.Events) > 0, "events", "must contain at least 1 event")
+	v.Check(validator.Unique(w.Events), "events", "must not contain duplicate events")
+
+	for i, e := range w.Events {
+		v.Check(validator.PermittedValue(e, WebhookEvents...), fmt.Sprintf("events[%d]", i), "must be a known event")
+	}
+
+	if w.MinGrade != nil {
+		v.Check(*w.MinGrade >= 0 && *w.MinGrade <= MaxGrade, "min_grade", fmt.Sprintf("must be between 0 and %d", MaxGrade))
+	}
+
+	if v.Valid() {
+		return nil
+	}
+
+	return v.Errors
+}
+
+// cgnatPrefix is the shared address space carriers use behind NAT, which is as
+// private as RFC 1918 as far as webhooks are concerned.
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicWebhookAddr reports whether webhooks may be delivered to addr. Loopback,
+// private, link-local and other addresses that only make sense inside our own
+// network are refused, so webhooks can't be used to probe it.
+func PublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!cgnatPrefix.Contains(addr)
+}
+
+// publicWebhookHost reports whether a webhook URL's host could be public. Host
+// names are only checked for the obvious local ones here; where they resolve
+// to is checked again when a delivery is sent.
+func publicWebhookHost(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return PublicWebhookAddr(addr)
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event queued for a webhook, along with how sending
+// it has gone so far.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id"`
+	WebhookID      uuid.UUID             `json:"webhook_id"`
+	Event          events.Type           `json:"event"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at"`
+	LastStatusCode *int                  `json:"last_status_code"`
+	LastError      *string               `json:"last_error"`
+	DeliveredAt    *time.Time            `json:"delivered_at"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+// PendingWebhookDelivery is a delivery that is due to be sent, with where to
+// send it and the secret to sign it with.
+type PendingWebhookDelivery struct {
+	ID       uuid.UUID
+	Event    events.Type
+	Payload  []byte
+	Attempts int
+	URL      string
+	Secret   string
+}
+
+// CreateWebhook saves a webhook with a newly generated secret.
+func (d *DB) CreateWebhook(w *Webhook) error {
+	secret := make([]byte, 32)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return fmt.Errorf("error generating webhook secret: %v", err)
+	}
+
+	w.Secret = hex.EncodeToString(secret)
+
+	err = d.QueryRow(`
+		INSERT INTO webhooks (board_id, url, secret, events, min_grade, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, w.BoardID, w.URL, w.Secret, pq.Array(w.Events), w.MinGrade, w.CreatedBy).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating webhook: %v", err)
+	}
+
+	return nil
+}
+
+// GetWebhooks returns a board's webhooks, oldest first, without their secrets.
+func (d *DB) GetWebhooks(boardID uuid.UUID) ([]Webhook, error) {
+	rows, err := d.Query(`
+		SELECT id, board_id, url, events, min_grade, created_by, created_at
+		FROM webhooks
+		WHERE board_id = $1
+		ORDER BY created_at
+	`, boardID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhooks: %v", err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+
+	for rows.Next() {
+		var (
+			w     Webhook
+			types []string
+		)
+
+		err := rows.Scan(&w.ID, &w.BoardID, &w.URL, pq.Array(&types), &w.MinGrade, &w.CreatedBy, &w.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook: %v", err)
+		}
+
+		w.Events = make([]events.Type, len(types))
+		for i, t := range types {
+			w.Events[i] = events.Type(t)
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %v", err)
+	}
+
+	return webhooks, nil
+}
+
+func (d *DB) DeleteWebhook(boardID, webhookID uuid.UUID) error {
+	result, err := d.Exec(`DELETE FROM webhooks WHERE id = $1 AND board_id = $2`, webhookID, boardID)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking deleted webhook: %v", err)
+	}
+
+	if rows == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns one page of a webhook's deliveries, newest
+// first.
+func (d *DB) GetWebhookDeliveries(boardID, webhookID uuid.UUID, p Pagination) ([]WebhookDelivery, Metadata, error) {
+	var exists bool
+
+	err := d.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM webhooks WHERE id = $1 AND board_id = $2)
+	`, webhookID, boardID).Scan(&exists)
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("error checking if webhook exists: %v", err)
+	}
+
+	if !exists {
+		return nil, Metadata{}, ErrWebhookNotFound
+	}
+
+	rows, err := d.Query(`
+		SELECT
+			COUNT(*) OVER (), id, webhook_id, event, payload, status, attempts,
+			CASE WHEN status = 'pending' THEN next_attempt_at END,
+			last_status_code, last_error, delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`, webhookID, p.limit(), p.offset())
+	if err != nil {
+		return nil, Metadata{}, fmt.Errorf("error querying webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var (
+		totalRecords int
+		deliveries   = []WebhookDelivery{}
+	)
+
+	for rows.Next() {
+		var w WebhookDelivery
+
+		err := rows.Scan(
+			&totalRecords, &w.ID, &w.WebhookID, &w.Event, &w.Payload, &w.Status, &w.Attempts,
+			&w.NextAttemptAt, &w.LastStatusCode, &w.LastError, &w.DeliveredAt, &w.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, fmt.Errorf("error scanning webhook delivery: %v", err)
+		}
+
+		deliveries = append(deliveries, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, fmt.Errorf("error iterating webhook deliveries: %v", err)
+	}
+
+	return deliveries, calculateMetadata(totalRecords, p), nil
+}
+
+// ClaimWebhookDeliveries returns up to limit deliveries that are due, and
+// holds them back from other claims for lease. A delivery that isn't recorded
+// before its lease runs out, say because the server stopped, is sent again.
+func (d *DB) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]PendingWebhookDelivery, error) {
+	rows, err := d.Query(`
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.event, d.payload, d.attempts, w.url, w.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []PendingWebhookDelivery
+
+	for rows.Next() {
+		var p PendingWebhookDelivery
+
+		err := rows.Scan(&p.ID, &p.Event, &p.Payload, &p.Attempts, &p.URL, &p.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %v", err)
+		}
+
+		deliveries = append(deliveries, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %v", err)
+	}
+
+	return deliveries, nil
+}
+
+// RecordWebhookAttempt records the outcome of sending a delivery. A delivery
+// that wasn't delivered is retried at retryAt, or given up on when retryAt is
+// nil.
+func (d *DB) RecordWebhookAttempt(deliveryID uuid.UUID, statusCode *int, attemptErr *string, delivered bool, retryAt *time.Time) error {
+	status := WebhookDeliveryPending
+
+	switch {
+	case delivered:
+		status = WebhookDeliveryDelivered
+	case retryAt == nil:
+		status = WebhookDeliveryFailed
+	}
+
+	_, err := d.Exec(`
+		UPDATE webhook_deliveries
+		SET
+			status = $2,
+			attempts = attempts + 1,
+			next_attempt_at = COALESCE($3, next_attempt_at),
+			last_status_code = $4,
+			last_error = $5,
+			delivered_at = CASE WHEN $6 THEN NOW() END
+		WHERE id = $1
+	`, deliveryID, status, retryAt, statusCode, attemptErr, delivered)
+	if err != nil {
+		return fmt.Errorf("error recording webhook attempt: %v", err)
+	}
+
+	return nil
+}
+
+// enqueueWebhooks queues an event for every webhook on its board that
+// subscribes to it, as part of tx so the event is only sent if the change it
+// describes is committed. grade is the grade of the problem the event is
+// about, if any, for webhooks that only want hard problems.
+func enqueueWebhooks(tx *sql.Tx, e events.Event, grade *int) error {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshaling webhook payload: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $2, $3
+		FROM webhooks
+		WHERE board_id = $1
+			AND $2 = ANY(events)
+			AND (min_grade IS NULL OR $4::integer >= min_grade)
+	`, e.BoardID, string(e.Type), payload, grade)
+	if err != nil {
+		return fmt.Errorf("error queueing webhook deliveries: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestPublicWebhookAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "8.8.8.8", want: true},
+		{addr: "2606:4700:4700::1111", want: true},
+		{addr: "127.0.0.1", want: false},
+		{addr: "::1", want: false},
+		{addr: "::ffff:127.0.0.1", want: false},
+		{addr: "10.1.2.3", want: false},
+		{addr: "172.16.0.1", want: false},
+		{addr: "192.168.1.1", want: false},
+		{addr: "169.254.169.254", want: false},
+		{addr: "fe80::1", want: false},
+		{addr: "fd00::1", want: false},
+		{addr: "100.64.0.1", want: false},
+		{addr: "0.0.0.0", want: false},
+		{addr: "224.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		got := PublicWebhookAddr(netip.MustParseAddr(tt.addr))
+
+		if got != tt.want {
+			t.Errorf("PublicWebhookAddr(%s): got %t, want %t", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestPublicWebhookHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "example.com", want: true},
+		{host: "8.8.8.8", want: true},
+		{host: "localhost", want: false},
+		{host: "LOCALHOST.", want: false},
+		{host: "api.localhost", want: false},
+		{host: "169.254.169.254", want: false},
+	}
+
+	for _, tt := range tests {
+		got := publicWebhookHost(tt.host)
+
+		if got != tt.want {
+			t.Errorf("publicWebhookHost(%q): got %t, want %t", tt.host, got, tt.want)
+		}
+	}
+}
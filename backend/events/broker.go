@@ -0,0 +1,124 @@
+// Package events fans out changes on boards to anyone listening, such as
+// clients following a board over server-sent events.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	ProblemCreated    Type = "problem-created"
+	ProblemPublished  Type = "problem-published"
+	ProblemUpdated    Type = "problem-updated"
+	HoldLayoutChanged Type = "hold-layout-changed"
+	AttemptLogged     Type = "attempt-logged"
+	ProblemSent       Type = "problem-sent"
+	CommentCreated    Type = "comment-created"
+)
+
+// Event is something that happened on a board. Data is sent to listeners as
+// JSON.
+type Event struct {
+	Type    Type      `json:"type"`
+	BoardID uuid.UUID `json:"board_id"`
+	Data    any       `json:"data"`
+	At      time.Time `json:"at"`
+}
+
+// subscriberBuffer is how many events a slow subscriber can fall behind by
+// before it starts missing them.
+const subscriberBuffer = 32
+
+// Broker passes published events on to the subscribers of their board. It is
+// safe for concurrent use.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan Event]struct{}
+	closed      bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[uuid.UUID]map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel of events on a board, and a function to stop
+// receiving them. The channel is closed when the broker closes.
+func (b *Broker) Subscribe(boardID uuid.UUID) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if b.subscribers[boardID] == nil {
+		b.subscribers[boardID] = make(map[chan Event]struct{})
+	}
+
+	b.subscribers[boardID][ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[boardID][ch]; !ok {
+			return
+		}
+
+		delete(b.subscribers[boardID], ch)
+
+		if len(b.subscribers[boardID]) == 0 {
+			delete(b.subscribers, boardID)
+		}
+
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish sends an event to the subscribers of its board without waiting for
+// them. Subscribers that have fallen too far behind miss the event.
+func (b *Broker) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[e.BoardID] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Close ends every subscription and drops any later events.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.closed = true
+
+	for _, subs := range b.subscribers {
+		for ch := range subs {
+			close(ch)
+		}
+	}
+
+	b.subscribers = nil
+}
@@ -0,0 +1,336 @@
+// Package grading predicts problem grades from the geometry of their holds,
+// using a ridge regression trained on problems people have already sent.
+package grading
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/vizvim/bloc/backend/db"
+)
+
+// MinSamples is the fewest graded problems a model can be trained on.
+const MinSamples = 10
+
+// ridgePenalty keeps weights small so a handful of odd problems can't swing
+// the model, which matters while boards only have a few graded problems.
+const ridgePenalty = 1.0
+
+// crossValidationFolds is how many parts the samples are split into to measure
+// a model's error on problems it wasn't trained on.
+const crossValidationFolds = 5
+
+var (
+	ErrTooFewSamples = fmt.Errorf("at least %d graded problems with sends are needed to train", MinSamples)
+	ErrStaleModel    = errors.New("grade model was trained on different features and must be retrained")
+)
+
+// FeatureNames describes each value returned by Features, in order. A model is
+// only usable while its features match these.
+var FeatureNames = []string{
+	"hand_holds",
+	"foot_holds",
+	"mean_hold_area",
+	"min_hold_area",
+	"mean_move",
+	"max_move",
+	"height",
+	"campus",
+	"feet_follow_hands",
+	"no_matching",
+}
+
+// Prediction is a suggested grade on the V scale. Confidence is the chance the
+// true grade rounds to Grade, assuming the model's errors are normal.
+type Prediction struct {
+	Grade      int     `json:"grade"`
+	Raw        float64 `json:"raw"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Features describes a problem for the model. Holds must have their vertices.
+// Moves are measured between the centroids of consecutive non-foot holds from
+// the bottom of the board to the top.
+func Features(rules db.ProblemRules, holds []db.ProblemHold) []float64 {
+	var (
+		hands, feet        float64
+		totalArea, minArea float64
+		points             []db.Point
+	)
+
+	minArea = math.Inf(1)
+
+	for _, h := range holds {
+		hold := db.Hold{Vertices: h.Vertices}
+		area := hold.Area()
+
+		totalArea += area
+		minArea = math.Min(minArea, area)
+
+		if h.Type == db.HoldTypeFoot {
+			feet++
+			continue
+		}
+
+		hands++
+
+		points = append(points, hold.Centroid())
+	}
+
+	if len(holds) == 0 {
+		minArea = 0
+	}
+
+	// Image y grows downwards, so the bottom of the board comes first
+	sort.Slice(points, func(i, j int) bool { return points[i].Y > points[j].Y })
+
+	var totalMove, maxMove, height float64
+
+	for i := 1; i < len(points); i++ {
+		move := math.Hypot(points[i].X-points[i-1].X, points[i].Y-points[i-1].Y)
+		totalMove += move
+		maxMove = math.Max(maxMove, move)
+	}
+
+	meanMove := 0.0
+	if len(points) > 1 {
+		meanMove = totalMove / float64(len(points)-1)
+		height = points[0].Y - points[len(points)-1].Y
+	}
+
+	meanArea := 0.0
+	if len(holds) > 0 {
+		meanArea = totalArea / float64(len(holds))
+	}
+
+	return []float64{
+		hands,
+		feet,
+		meanArea * 1000,
+		minArea * 1000,
+		meanMove,
+		maxMove,
+		height,
+		boolFeature(rules.Campus),
+		boolFeature(rules.FeetFollowHands),
+		boolFeature(rules.NoMatching),
+	}
+}
+
+func boolFeature(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// Train fits a model to graded samples. The returned model has no ID until it
+// is saved. Its RMSE comes from cross-validation, so it reflects how the model
+// does on problems it wasn't trained on.
+func Train(samples []db.GradeSample) (*db.GradeModel, error) {
+	if len(samples) < MinSamples {
+		return nil, ErrTooFewSamples
+	}
+
+	n := len(samples)
+
+	x := make([][]float64, n)
+	y := make([]float64, n)
+
+	for i, s := range samples {
+		x[i] = Features(s.Problem.Rules, s.Holds)
+		y[i] = float64(*s.Problem.Grade)
+	}
+
+	model, err := fit(x, y)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every sample is predicted by a model fitted to the other folds
+	var squaredError float64
+
+	for fold := range crossValidationFolds {
+		var trainX, testX [][]float64
+
+		var trainY, testY []float64
+
+		for i := range n {
+			if i%crossValidationFolds == fold {
+				testX = append(testX, x[i])
+				testY = append(testY, y[i])
+			} else {
+				trainX = append(trainX, x[i])
+				trainY = append(trainY, y[i])
+			}
+		}
+
+		foldModel, err := fit(trainX, trainY)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, features := range testX {
+			raw := predictRaw(foldModel, features)
+			squaredError += (raw - testY[i]) * (raw - testY[i])
+		}
+	}
+
+	model.RMSE = math.Sqrt(squaredError / float64(n))
+
+	return model, nil
+}
+
+// fit solves the ridge regression for features x and grades y, leaving x as it
+// was.
+func fit(x [][]float64, y []float64) (*db.GradeModel, error) {
+	n := len(y)
+	k := len(FeatureNames)
+
+	means := make([]float64, k)
+	scales := make([]float64, k)
+	z := make([][]float64, n)
+
+	for i := range n {
+		z[i] = make([]float64, k)
+	}
+
+	for j := range k {
+		for i := range n {
+			means[j] += x[i][j] / float64(n)
+		}
+
+		for i := range n {
+			scales[j] += (x[i][j] - means[j]) * (x[i][j] - means[j]) / float64(n)
+		}
+
+		scales[j] = math.Sqrt(scales[j])
+		if scales[j] == 0 {
+			scales[j] = 1
+		}
+
+		for i := range n {
+			z[i][j] = (x[i][j] - means[j]) / scales[j]
+		}
+	}
+
+	intercept := 0.0
+	for _, v := range y {
+		intercept += v / float64(n)
+	}
+
+	// Solve (ZᵀZ + λI)w = Zᵀ(y - ȳ) on the standardised features
+	a := make([][]float64, k)
+	b := make([]float64, k)
+
+	for p := range k {
+		a[p] = make([]float64, k)
+
+		for q := range k {
+			for i := range n {
+				a[p][q] += z[i][p] * z[i][q]
+			}
+		}
+
+		a[p][p] += ridgePenalty
+
+		for i := range n {
+			b[p] += z[i][p] * (y[i] - intercept)
+		}
+	}
+
+	weights, err := solve(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &db.GradeModel{
+		Features:  FeatureNames,
+		Means:     means,
+		Scales:    scales,
+		Weights:   weights,
+		Intercept: intercept,
+		Samples:   n,
+	}, nil
+}
+
+// Predict suggests a grade for a problem. Holds must have their vertices.
+func Predict(model *db.GradeModel, rules db.ProblemRules, holds []db.ProblemHold) (Prediction, error) {
+	// Features are matched by name, so a model trained before one was renamed
+	// or moved isn't applied to the wrong values
+	k := len(FeatureNames)
+	if !slices.Equal(model.Features, FeatureNames) || len(model.Means) != k || len(model.Scales) != k || len(model.Weights) != k {
+		return Prediction{}, ErrStaleModel
+	}
+
+	raw := predictRaw(model, Features(rules, holds))
+	grade := int(math.Round(math.Max(0, math.Min(db.MaxGrade, raw))))
+
+	confidence := 1.0
+	if model.RMSE > 0 {
+		// Chance a normal error with the model's spread stays within half a grade
+		confidence = math.Erf(0.5 / (model.RMSE * math.Sqrt2))
+	}
+
+	return Prediction{Grade: grade, Raw: raw, Confidence: confidence}, nil
+}
+
+func predictRaw(model *db.GradeModel, features []float64) float64 {
+	raw := model.Intercept
+
+	for j, f := range features {
+		raw += model.Weights[j] * (f - model.Means[j]) / model.Scales[j]
+	}
+
+	return raw
+}
+
+// solve uses Gaussian elimination with partial pivoting to solve ax = b.
+func solve(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+
+	for col := range n {
+		pivot := col
+
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, errors.New("grade model has no unique solution")
+		}
+
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := a[row][col] / a[col][col]
+
+			for c := col; c < n; c++ {
+				a[row][c] -= factor * a[col][c]
+			}
+
+			b[row] -= factor * b[col]
+		}
+	}
+
+	x := make([]float64, n)
+
+	for row := n - 1; row >= 0; row-- {
+		sum := b[row]
+
+		for c := row + 1; c < n; c++ {
+			sum -= a[row][c] * x[c]
+		}
+
+		x[row] = sum / a[row][row]
+	}
+
+	return x, nil
+}
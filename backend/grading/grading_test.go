@@ -0,0 +1,266 @@
+package grading
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/vizvim/bloc/backend/db"
+)
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       [][]float64
+		b       []float64
+		want    []float64
+		wantErr bool
+	}{
+		{
+			name: "identity",
+			a:    [][]float64{{1, 0}, {0, 1}},
+			b:    []float64{3, -2},
+			want: []float64{3, -2},
+		},
+		{
+			name: "two equations",
+			a:    [][]float64{{2, 1}, {1, 3}},
+			b:    []float64{5, 10},
+			want: []float64{1, 3},
+		},
+		{
+			name: "zero on the diagonal needs a pivot",
+			a:    [][]float64{{0, 2, 1}, {1, 1, 0}, {2, 0, 3}},
+			b:    []float64{7, 3, 11},
+			want: []float64{1, 2, 3},
+		},
+		{
+			name:    "singular",
+			a:       [][]float64{{1, 2}, {2, 4}},
+			b:       []float64{3, 6},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := solve(tt.a, tt.b)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for i := range tt.want {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+// ladder returns a foot hold under a line of hand holds climbing the board.
+func ladder(hands int) []db.ProblemHold {
+	square := func(t db.HoldType, x, y float64) db.ProblemHold {
+		return db.ProblemHold{Type: t, Vertices: []db.Point{{X: x, Y: y}, {X: x + 0.02, Y: y}, {X: x + 0.02, Y: y + 0.02}, {X: x, Y: y + 0.02}}}
+	}
+
+	holds := []db.ProblemHold{square(db.HoldTypeFoot, 0.5, 0.95)}
+	for i := range hands {
+		holds = append(holds, square(db.HoldTypeHand, 0.5, 0.9-0.07*float64(i)))
+	}
+
+	return holds
+}
+
+func TestTrain(t *testing.T) {
+	sample := func(grade, hands int) db.GradeSample {
+		return db.GradeSample{Problem: db.Problem{Grade: &grade}, Holds: ladder(hands)}
+	}
+
+	tests := []struct {
+		name    string
+		samples []db.GradeSample
+		wantErr error
+		check   func(t *testing.T, model *db.GradeModel)
+	}{
+		{
+			name:    "too few samples",
+			samples: []db.GradeSample{sample(1, 3), sample(2, 4), sample(3, 5)},
+			wantErr: ErrTooFewSamples,
+		},
+		{
+			// With nothing to tell them apart every problem is predicted as the
+			// mean of the other folds, which misses by more than the in-sample
+			// spread of sqrt(8.25)
+			name: "rmse is measured on held-out problems",
+			samples: []db.GradeSample{
+				sample(0, 5), sample(1, 5), sample(2, 5), sample(3, 5), sample(4, 5),
+				sample(5, 5), sample(6, 5), sample(7, 5), sample(8, 5), sample(9, 5),
+			},
+			check: func(t *testing.T, model *db.GradeModel) {
+				if want := math.Sqrt(9.375); math.Abs(model.RMSE-want) > 1e-9 {
+					t.Errorf("rmse: got %v, want %v", model.RMSE, want)
+				}
+			},
+		},
+		{
+			name: "longer problems are graded harder",
+			samples: []db.GradeSample{
+				sample(0, 3), sample(1, 4), sample(2, 5), sample(3, 6), sample(4, 7),
+				sample(5, 8), sample(6, 9), sample(7, 10), sample(8, 11), sample(9, 12),
+			},
+			check: func(t *testing.T, model *db.GradeModel) {
+				if model.Samples != 10 {
+					t.Errorf("samples: got %d, want 10", model.Samples)
+				}
+
+				short, err := Predict(model, db.ProblemRules{}, ladder(4))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				long, err := Predict(model, db.ProblemRules{}, ladder(11))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if short.Raw >= long.Raw {
+					t.Errorf("got %v for the short problem and %v for the long one", short.Raw, long.Raw)
+				}
+
+				if model.RMSE <= 0 || model.RMSE >= 2 {
+					t.Errorf("rmse: got %v, want between 0 and 2", model.RMSE)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := Train(tt.samples)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			tt.check(t, model)
+		})
+	}
+}
+
+func TestPredict(t *testing.T) {
+	// A model that only looks at campus, adding three grades to a V4
+	model := func(intercept, rmse float64) *db.GradeModel {
+		k := len(FeatureNames)
+		m := &db.GradeModel{
+			Features:  FeatureNames,
+			Means:     make([]float64, k),
+			Scales:    make([]float64, k),
+			Weights:   make([]float64, k),
+			Intercept: intercept,
+			RMSE:      rmse,
+		}
+
+		for j, name := range FeatureNames {
+			m.Scales[j] = 1
+
+			if name == "campus" {
+				m.Weights[j] = 3
+			}
+		}
+
+		return m
+	}
+
+	renamed := model(4, 0)
+	renamed.Features = append([]string{"hands"}, FeatureNames[1:]...)
+
+	reordered := model(4, 0)
+	reordered.Features = append([]string{FeatureNames[1], FeatureNames[0]}, FeatureNames[2:]...)
+
+	tests := []struct {
+		name    string
+		model   *db.GradeModel
+		rules   db.ProblemRules
+		want    Prediction
+		wantErr error
+	}{
+		{
+			name:  "intercept alone",
+			model: model(4, 0),
+			want:  Prediction{Grade: 4, Raw: 4, Confidence: 1},
+		},
+		{
+			name:  "weighted feature",
+			model: model(4, 0),
+			rules: db.ProblemRules{Campus: true},
+			want:  Prediction{Grade: 7, Raw: 7, Confidence: 1},
+		},
+		{
+			name:  "rounds to the nearest grade",
+			model: model(4.6, 0),
+			want:  Prediction{Grade: 5, Raw: 4.6, Confidence: 1},
+		},
+		{
+			name:  "clamped to the scale",
+			model: model(db.MaxGrade, 0),
+			rules: db.ProblemRules{Campus: true},
+			want:  Prediction{Grade: db.MaxGrade, Raw: db.MaxGrade + 3, Confidence: 1},
+		},
+		{
+			name:  "confidence from rmse",
+			model: model(4, 0.5),
+			want:  Prediction{Grade: 4, Raw: 4, Confidence: math.Erf(1 / math.Sqrt2)},
+		},
+		{
+			name:    "renamed feature",
+			model:   renamed,
+			wantErr: ErrStaleModel,
+		},
+		{
+			name:    "reordered features",
+			model:   reordered,
+			wantErr: ErrStaleModel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Predict(tt.model, tt.rules, ladder(4))
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Grade != tt.want.Grade || math.Abs(got.Raw-tt.want.Raw) > 1e-9 || math.Abs(got.Confidence-tt.want.Confidence) > 1e-9 {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
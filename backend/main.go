@@ -8,6 +8,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/vizvim/bloc/backend/api"
 	"github.com/vizvim/bloc/backend/db"
+	"github.com/vizvim/bloc/backend/grading"
 )
 
 func main() {
@@ -18,6 +19,17 @@ func main() {
 		log.Fatalf("error connecting to database: %v", err)
 	}
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "train-grades":
+			trainGrades(&logger, db)
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+
+		return
+	}
+
 	server := api.NewServer(
 		&logger,
 		db,
@@ -32,6 +44,31 @@ func main() {
 	server.Start()
 }
 
+// trainGrades fits a new grade model to every graded problem that has been
+// sent and saves it for the API to use.
+func trainGrades(logger *zerolog.Logger, datastore *db.DB) {
+	samples, err := datastore.GetGradeTrainingSet()
+	if err != nil {
+		log.Fatalf("error loading training set: %v", err)
+	}
+
+	model, err := grading.Train(samples)
+	if err != nil {
+		log.Fatalf("error training grade model: %v", err)
+	}
+
+	err = datastore.SaveGradeModel(model)
+	if err != nil {
+		log.Fatalf("error saving grade model: %v", err)
+	}
+
+	logger.Info().
+		Str("modelID", model.ID.String()).
+		Int("samples", model.Samples).
+		Float64("rmse", model.RMSE).
+		Msg("trained grade model")
+}
+
 func initializeLogger() zerolog.Logger {
 	logger := zerolog.New(os.Stderr).
 		With().
@@ -0,0 +1,6 @@
+ALTER TABLE problems
+    DROP COLUMN IF EXISTS feet_follow_hands,
+    DROP COLUMN IF EXISTS no_matching,
+    DROP COLUMN IF EXISTS campus,
+    DROP COLUMN IF EXISTS footless_start,
+    DROP COLUMN IF EXISTS marked_feet_only;
@@ -0,0 +1,6 @@
+ALTER TABLE problems
+    ADD COLUMN feet_follow_hands BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN no_matching BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN campus BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN footless_start BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN marked_feet_only BOOLEAN NOT NULL DEFAULT FALSE;
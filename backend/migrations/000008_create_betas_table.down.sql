@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS beta_moves;
+DROP TABLE IF EXISTS betas;
+DROP TYPE IF EXISTS limb;
@@ -0,0 +1,20 @@
+CREATE TYPE limb AS ENUM ('left_hand', 'right_hand', 'left_foot', 'right_foot');
+
+CREATE TABLE betas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT unique_beta_per_user UNIQUE (problem_id, user_id)
+);
+
+CREATE TABLE beta_moves (
+    beta_id UUID NOT NULL REFERENCES betas(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    limb limb NOT NULL,
+    hold_id UUID NOT NULL REFERENCES holds(id) ON DELETE CASCADE,
+    PRIMARY KEY (beta_id, position)
+);
+
+CREATE INDEX idx_betas_problem_id ON betas(problem_id);
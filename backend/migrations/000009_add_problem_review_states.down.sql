@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS problem_transitions;
+
+UPDATE problems SET status = 'DRAFT' WHERE status = 'IN_REVIEW';
+UPDATE problems SET status = 'PUBLISHED' WHERE status = 'ARCHIVED';
+
+ALTER TABLE problems ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE problem_status RENAME TO problem_status_old;
+CREATE TYPE problem_status AS ENUM ('DRAFT', 'PUBLISHED');
+ALTER TABLE problems ALTER COLUMN status TYPE problem_status USING status::text::problem_status;
+ALTER TABLE problems ALTER COLUMN status SET DEFAULT 'DRAFT';
+DROP TYPE problem_status_old;
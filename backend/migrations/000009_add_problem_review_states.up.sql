@@ -0,0 +1,15 @@
+ALTER TYPE problem_status ADD VALUE IF NOT EXISTS 'IN_REVIEW' AFTER 'DRAFT';
+ALTER TYPE problem_status ADD VALUE IF NOT EXISTS 'ARCHIVED' AFTER 'PUBLISHED';
+
+CREATE TABLE problem_transitions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    action VARCHAR(10) CHECK (action IN ('submit', 'approve', 'reject', 'archive')) NOT NULL,
+    from_status problem_status NOT NULL,
+    to_status problem_status NOT NULL,
+    actor_id UUID NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_problem_transitions_problem_id ON problem_transitions(problem_id);
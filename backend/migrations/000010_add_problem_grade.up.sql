@@ -0,0 +1,3 @@
+-- Grades are stored on the V scale, so 4 is V4
+ALTER TABLE problems
+    ADD COLUMN grade SMALLINT CHECK (grade BETWEEN 0 AND 17);
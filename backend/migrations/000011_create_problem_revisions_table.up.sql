@@ -0,0 +1,39 @@
+CREATE TABLE problem_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    status problem_status NOT NULL,
+    grade SMALLINT,
+    rules JSONB NOT NULL,
+    holds JSONB NOT NULL,
+    editor_id UUID NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT unique_problem_revision_number UNIQUE (problem_id, number),
+    CONSTRAINT check_holds_is_array CHECK (jsonb_typeof(holds) = 'array')
+);
+
+-- Existing problems start their history with what they look like today
+INSERT INTO problem_revisions (problem_id, number, name, status, grade, rules, holds, editor_id, created_at)
+SELECT
+    p.id,
+    1,
+    p.name,
+    p.status,
+    p.grade,
+    jsonb_build_object(
+        'feet_follow_hands', p.feet_follow_hands,
+        'no_matching', p.no_matching,
+        'campus', p.campus,
+        'footless_start', p.footless_start,
+        'marked_feet_only', p.marked_feet_only
+    ),
+    COALESCE(
+        (SELECT jsonb_agg(jsonb_build_object('hold_id', ph.hold_id, 'type', ph.type))
+         FROM problem_holds ph
+         WHERE ph.problem_id = p.id),
+        '[]'::jsonb
+    ),
+    p.setter_id,
+    p.created_at
+FROM problems p;
@@ -0,0 +1,4 @@
+ALTER TABLE problems
+    ADD COLUMN parent_id UUID REFERENCES problems(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_problem_parent_id ON problems(parent_id);
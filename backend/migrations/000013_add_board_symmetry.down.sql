@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS hold_mirrors;
+
+ALTER TABLE boards
+    DROP COLUMN IF EXISTS owner_id,
+    DROP COLUMN IF EXISTS symmetric;
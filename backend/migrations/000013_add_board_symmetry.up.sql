@@ -0,0 +1,14 @@
+-- Boards created before owners existed belong to the placeholder user
+ALTER TABLE boards
+    ADD COLUMN owner_id UUID NOT NULL DEFAULT '10000000-0000-0000-0000-000000000001',
+    ADD COLUMN symmetric BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE boards ALTER COLUMN owner_id DROP DEFAULT;
+
+CREATE TABLE hold_mirrors (
+    board_id UUID NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    hold_id UUID PRIMARY KEY REFERENCES holds(id) ON DELETE CASCADE,
+    mirror_hold_id UUID NOT NULL REFERENCES holds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_hold_mirrors_board_id ON hold_mirrors(board_id);
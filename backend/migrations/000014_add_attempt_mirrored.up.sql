@@ -0,0 +1,2 @@
+ALTER TABLE attempts
+    ADD COLUMN mirrored BOOLEAN NOT NULL DEFAULT FALSE;
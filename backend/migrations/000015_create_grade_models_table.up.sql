@@ -0,0 +1,7 @@
+CREATE TABLE grade_models (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    model JSONB NOT NULL,
+    samples INTEGER NOT NULL,
+    rmse DOUBLE PRECISION NOT NULL,
+    trained_at TIMESTAMP DEFAULT NOW()
+);
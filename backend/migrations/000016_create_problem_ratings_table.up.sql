@@ -0,0 +1,9 @@
+CREATE TABLE problem_ratings (
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    stars SMALLINT NOT NULL CHECK (stars BETWEEN 1 AND 5),
+    rated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (problem_id, user_id)
+);
+
+CREATE INDEX idx_problem_ratings_user_id ON problem_ratings(user_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS circuit_problems;
+DROP TABLE IF EXISTS circuits;
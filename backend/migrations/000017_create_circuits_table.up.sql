@@ -0,0 +1,20 @@
+CREATE TABLE circuits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id UUID NOT NULL,
+    public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE circuit_problems (
+    circuit_id UUID NOT NULL REFERENCES circuits(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    PRIMARY KEY (circuit_id, position),
+    CONSTRAINT unique_circuit_problem UNIQUE (circuit_id, problem_id)
+);
+
+CREATE INDEX idx_circuits_owner_id ON circuits(owner_id);
+CREATE INDEX idx_circuit_problems_problem_id ON circuit_problems(problem_id);
@@ -0,0 +1,13 @@
+CREATE TABLE favourites (
+    user_id UUID NOT NULL,
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (user_id, problem_id)
+);
+
+CREATE TABLE projects (
+    user_id UUID NOT NULL,
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (user_id, problem_id)
+);
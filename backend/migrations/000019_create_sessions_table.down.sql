@@ -0,0 +1,4 @@
+ALTER TABLE attempts
+    DROP COLUMN IF EXISTS session_id;
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,56 @@
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMP,
+    auto BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT check_auto_session_ended CHECK (NOT auto OR ended_at IS NOT NULL)
+);
+
+-- A user can only have one session open at a time
+CREATE UNIQUE INDEX unique_open_session ON sessions(user_id) WHERE ended_at IS NULL;
+CREATE INDEX idx_sessions_user_id_started_at ON sessions(user_id, started_at);
+
+ALTER TABLE attempts
+    ADD COLUMN session_id UUID REFERENCES sessions(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_attempts_session_id ON attempts(session_id);
+
+-- Group existing attempts into sessions wherever a user rested for more than
+-- two hours between attempts
+CREATE TEMPORARY TABLE attempt_sessions AS
+WITH gaps AS (
+    SELECT
+        id,
+        user_id,
+        attempted_at,
+        CASE
+            WHEN attempted_at - LAG(attempted_at) OVER (PARTITION BY user_id ORDER BY attempted_at, id) <= INTERVAL '2 hours' THEN 0
+            ELSE 1
+        END AS starts_session
+    FROM attempts
+)
+SELECT
+    id AS attempt_id,
+    user_id,
+    attempted_at,
+    SUM(starts_session) OVER (PARTITION BY user_id ORDER BY attempted_at, id) AS session_number
+FROM gaps;
+
+CREATE TEMPORARY TABLE backfilled_sessions AS
+SELECT gen_random_uuid() AS id, user_id, session_number, MIN(attempted_at) AS started_at, MAX(attempted_at) AS ended_at
+FROM attempt_sessions
+GROUP BY user_id, session_number;
+
+INSERT INTO sessions (id, user_id, started_at, ended_at, auto)
+SELECT id, user_id, started_at, ended_at, TRUE
+FROM backfilled_sessions;
+
+UPDATE attempts a
+SET session_id = bs.id
+FROM attempt_sessions s
+JOIN backfilled_sessions bs ON bs.user_id = s.user_id AND bs.session_number = s.session_number
+WHERE a.id = s.attempt_id;
+
+DROP TABLE attempt_sessions;
+DROP TABLE backfilled_sessions;
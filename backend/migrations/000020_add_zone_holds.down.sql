@@ -0,0 +1,11 @@
+ALTER TABLE attempts
+    DROP COLUMN IF EXISTS zone;
+
+-- Enum values can't be dropped, so zone holds go back to being hand holds on
+-- a rebuilt type
+UPDATE problem_holds SET type = 'hand' WHERE type = 'zone';
+
+ALTER TYPE hold_type RENAME TO hold_type_old;
+CREATE TYPE hold_type AS ENUM ('start', 'hand', 'foot', 'finish');
+ALTER TABLE problem_holds ALTER COLUMN type TYPE hold_type USING type::text::hold_type;
+DROP TYPE hold_type_old;
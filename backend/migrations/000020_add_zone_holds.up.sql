@@ -0,0 +1,4 @@
+ALTER TYPE hold_type ADD VALUE 'zone' AFTER 'hand';
+
+ALTER TABLE attempts
+    ADD COLUMN zone BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_attempts_user_id_problem_id;
+DROP TABLE IF EXISTS competition_competitors;
+DROP TABLE IF EXISTS competition_problems;
+DROP TABLE IF EXISTS competitions;
+DROP TYPE IF EXISTS competition_format;
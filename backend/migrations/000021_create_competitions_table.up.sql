@@ -0,0 +1,31 @@
+CREATE TYPE competition_format AS ENUM ('ifsc', 'points', 'flash_bonus');
+
+CREATE TABLE competitions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    owner_id UUID NOT NULL,
+    format competition_format NOT NULL,
+    flash_bonus INTEGER NOT NULL DEFAULT 0 CHECK (flash_bonus >= 0),
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT check_competition_window CHECK (ends_at > starts_at)
+);
+
+CREATE TABLE competition_problems (
+    competition_id UUID NOT NULL REFERENCES competitions(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    points INTEGER NOT NULL CHECK (points >= 0),
+    PRIMARY KEY (competition_id, position),
+    CONSTRAINT unique_competition_problem UNIQUE (competition_id, problem_id)
+);
+
+CREATE TABLE competition_competitors (
+    competition_id UUID NOT NULL REFERENCES competitions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    registered_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (competition_id, user_id)
+);
+
+CREATE INDEX idx_attempts_user_id_problem_id ON attempts(user_id, problem_id);
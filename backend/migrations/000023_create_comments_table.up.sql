@@ -0,0 +1,16 @@
+CREATE TABLE comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    edited_at TIMESTAMP,
+    -- Deleted comments keep their place in the thread so replies still make
+    -- sense, but lose their body
+    deleted_at TIMESTAMP,
+    deleted_by UUID
+);
+
+CREATE INDEX idx_comments_problem_id ON comments(problem_id, created_at);
+CREATE INDEX idx_comments_parent_id ON comments(parent_id);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS reports;
+DROP TYPE IF EXISTS moderation_action;
+DROP TYPE IF EXISTS report_status;
+DROP TYPE IF EXISTS report_reason;
+DROP TYPE IF EXISTS report_target;
+DROP TABLE IF EXISTS moderators;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS hidden_at;
+ALTER TABLE problems DROP COLUMN IF EXISTS hidden_at;
+ALTER TABLE boards DROP COLUMN IF EXISTS hidden_at;
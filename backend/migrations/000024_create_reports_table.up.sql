@@ -0,0 +1,33 @@
+ALTER TABLE boards ADD COLUMN hidden_at TIMESTAMP;
+ALTER TABLE problems ADD COLUMN hidden_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN hidden_at TIMESTAMP;
+
+-- Site-wide moderators, who can act on reports about any content. Board
+-- owners moderate the problems and comments on their own boards.
+CREATE TABLE moderators (
+    user_id UUID PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TYPE report_target AS ENUM ('problem', 'comment', 'board');
+CREATE TYPE report_reason AS ENUM ('spam', 'offensive', 'inappropriate', 'other');
+CREATE TYPE report_status AS ENUM ('open', 'actioned', 'dismissed');
+CREATE TYPE moderation_action AS ENUM ('hide', 'delete', 'dismiss');
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    target_type report_target NOT NULL,
+    target_id UUID NOT NULL,
+    board_id UUID NOT NULL,
+    reporter_id UUID NOT NULL,
+    reason report_reason NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status report_status NOT NULL DEFAULT 'open',
+    action moderation_action,
+    resolved_by UUID,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX unique_open_report ON reports(target_type, target_id, reporter_id) WHERE status = 'open';
+CREATE INDEX idx_reports_open ON reports(board_id, created_at) WHERE status = 'open';
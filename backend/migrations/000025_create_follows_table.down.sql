@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_problem_transitions_approvals;
+DROP INDEX IF EXISTS idx_attempts_sends_by_user;
+DROP TABLE IF EXISTS follows;
@@ -0,0 +1,13 @@
+CREATE TABLE follows (
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (follower_id, followee_id),
+    CONSTRAINT check_not_self_follow CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX idx_follows_followee_id ON follows(followee_id);
+
+-- The feed reads sends and publishes by user, newest first
+CREATE INDEX idx_attempts_sends_by_user ON attempts(user_id, attempted_at) WHERE status = 'sent';
+CREATE INDEX idx_problem_transitions_approvals ON problem_transitions(created_at) WHERE action = 'approve';
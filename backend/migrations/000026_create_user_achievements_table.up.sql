@@ -0,0 +1,8 @@
+-- Achievements themselves are defined in code, so awards refer to them by
+-- their ID
+CREATE TABLE user_achievements (
+    user_id UUID NOT NULL,
+    achievement_id VARCHAR(64) NOT NULL,
+    awarded_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (user_id, achievement_id)
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_boards_organization_id;
+ALTER TABLE boards DROP COLUMN IF EXISTS public;
+ALTER TABLE boards DROP COLUMN IF EXISTS organization_id;
+DROP TABLE IF EXISTS organization_invites;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+DROP TYPE IF EXISTS organization_role;
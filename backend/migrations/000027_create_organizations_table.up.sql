@@ -0,0 +1,40 @@
+-- Organizations are gyms or households that own boards. Owners manage the
+-- organization and its boards, and members can climb on them.
+CREATE TYPE organization_role AS ENUM ('owner', 'member');
+
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE organization_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    role organization_role NOT NULL DEFAULT 'member',
+    joined_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE TABLE organization_invites (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    role organization_role NOT NULL DEFAULT 'member',
+    invited_by UUID NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT unique_organization_invite UNIQUE (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+CREATE INDEX idx_organization_invites_user_id ON organization_invites(user_id);
+
+ALTER TABLE boards ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+ALTER TABLE boards ADD COLUMN public BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Boards made before organizations were visible to everyone, so they stay
+-- that way
+UPDATE boards SET public = TRUE;
+
+CREATE INDEX idx_boards_organization_id ON boards(organization_id);
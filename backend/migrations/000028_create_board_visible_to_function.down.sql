@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS board_visible_to(UUID, UUID);
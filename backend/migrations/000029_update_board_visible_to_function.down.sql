@@ -0,0 +1,13 @@
+CREATE OR REPLACE FUNCTION board_visible_to(board UUID, viewer UUID) RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1
+        FROM boards b
+        WHERE b.id = board
+            AND (
+                b.public
+                OR b.owner_id = viewer
+                OR EXISTS (SELECT 1 FROM organization_members m WHERE m.organization_id = b.organization_id AND m.user_id = viewer)
+                OR EXISTS (SELECT 1 FROM moderators WHERE user_id = viewer)
+            )
+    )
+$$ LANGUAGE SQL STABLE;
@@ -22,6 +22,14 @@ func (v *Validator) AddError(key, message string) {
 	}
 }
 
+// AddErrors adds errors from validating a nested value, prefixing each of
+// their keys with the key of that value.
+func (v *Validator) AddErrors(prefix string, errs map[string]string) {
+	for key, message := range errs {
+		v.AddError(prefix+"."+key, message)
+	}
+}
+
 func (v *Validator) Check(ok bool, key, message string) {
 	if !ok {
 		v.AddError(key, message)
@@ -0,0 +1,249 @@
+// Package webhooks sends the events queued for a board's webhooks, signing
+// each one and retrying failures with exponential backoff.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/vizvim/bloc/backend/db"
+)
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of
+// the timestamp, a full stop and the body, keyed with the webhook's secret,
+// so receivers can check a delivery came from us and reject old ones.
+const (
+	EventHeader     = "X-Bloc-Event"
+	DeliveryHeader  = "X-Bloc-Delivery"
+	TimestampHeader = "X-Bloc-Timestamp"
+	SignatureHeader = "X-Bloc-Signature"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is marked
+	// as failed.
+	MaxAttempts = 8
+
+	// baseBackoff is the wait before the first retry, which doubles after
+	// each failure.
+	baseBackoff = 30 * time.Second
+
+	pollInterval = 2 * time.Second
+	batchSize    = 20
+	requestLimit = 10 * time.Second
+
+	// lease must be longer than it takes to send a batch, or deliveries are
+	// sent twice.
+	lease = 5 * time.Minute
+)
+
+type datastore interface {
+	ClaimWebhookDeliveries(limit int, lease time.Duration) ([]db.PendingWebhookDelivery, error)
+	RecordWebhookAttempt(deliveryID uuid.UUID, statusCode *int, attemptErr *string, delivered bool, retryAt *time.Time) error
+}
+
+// Dispatcher polls the outbox for due deliveries and sends them.
+type Dispatcher struct {
+	logger    zerolog.Logger
+	datastore datastore
+	client    *http.Client
+	cancel    context.CancelFunc
+	done      chan struct{}
+	once      sync.Once
+}
+
+func NewDispatcher(l *zerolog.Logger, datastore datastore) *Dispatcher {
+	return &Dispatcher{
+		logger:    l.With().Str("component", "webhookDispatcher").Logger(),
+		datastore: datastore,
+		client:    newClient(),
+		done:      make(chan struct{}),
+	}
+}
+
+// errForbiddenAddr is returned when a webhook's host resolves somewhere it
+// isn't allowed to.
+var errForbiddenAddr = errors.New("webhook address is not public")
+
+// newClient returns the client deliveries are sent with. It checks the address
+// each connection is actually made to, after DNS and on every redirect, so a
+// host name can't be pointed at our own network after it was registered. It
+// doesn't use a proxy, which would hide the address being dialled.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: requestLimit,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("error parsing dialled address: %v", err)
+			}
+
+			if !db.PublicWebhookAddr(addrPort.Addr()) {
+				return errForbiddenAddr
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: requestLimit,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: requestLimit,
+			MaxIdleConns:        batchSize,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// Start sends deliveries in the background until Stop is called.
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	go func() {
+		defer close(d.done)
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			d.dispatch(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels any deliveries in flight and waits for the dispatcher to
+// finish. Cancelled deliveries are retried on the next start once their
+// lease runs out.
+func (d *Dispatcher) Stop() {
+	d.once.Do(func() {
+		if d.cancel == nil {
+			return
+		}
+
+		d.cancel()
+		<-d.done
+	})
+}
+
+// dispatch sends everything that is due, a batch at a time.
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.datastore.ClaimWebhookDeliveries(batchSize, lease)
+		if err != nil {
+			d.logger.Error().Err(err).Msg("failed to claim webhook deliveries")
+			return
+		}
+
+		for _, delivery := range deliveries {
+			d.send(ctx, delivery)
+		}
+
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery db.PendingWebhookDelivery) {
+	logger := d.logger.With().Str("deliveryID", delivery.ID.String()).Str("url", delivery.URL).Logger()
+
+	statusCode, err := d.post(ctx, delivery)
+	if ctx.Err() != nil {
+		return
+	}
+
+	var (
+		attemptErr *string
+		retryAt    *time.Time
+		delivered  = err == nil
+	)
+
+	if err != nil {
+		message := err.Error()
+		attemptErr = &message
+
+		if attempts := delivery.Attempts + 1; attempts < MaxAttempts {
+			next := time.Now().Add(Backoff(attempts))
+			retryAt = &next
+		}
+
+		logger.Warn().Err(err).Int("attempts", delivery.Attempts+1).Msg("webhook delivery failed")
+	}
+
+	err = d.datastore.RecordWebhookAttempt(delivery.ID, statusCode, attemptErr, delivered, retryAt)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to record webhook attempt")
+	}
+}
+
+// post sends a delivery, returning the response status code if there was
+// one. Any response outside 2xx is an error.
+func (d *Dispatcher) post(ctx context.Context, delivery db.PendingWebhookDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.Event))
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return &resp.StatusCode, nil
+}
+
+// Sign returns the hex signature of a delivery sent at timestamp.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is how long to wait before retrying a delivery that has failed
+// attempts times.
+func Backoff(attempts int) time.Duration {
+	return baseBackoff << (attempts - 1)
+}
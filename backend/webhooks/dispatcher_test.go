@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"event":"problem.published"}`)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		payload   []byte
+		want      string
+	}{
+		{
+			name:      "signs timestamp and payload",
+			secret:    "s3cret",
+			timestamp: "1700000000",
+			payload:   payload,
+			want:      "be030ac2ee5a6ba6b429c9e638718a6423c9ffb1c8270c6b535dcfb50b44ccf9",
+		},
+		{
+			name:      "timestamp changes the signature",
+			secret:    "s3cret",
+			timestamp: "1700000001",
+			payload:   payload,
+			want:      "78794dfaa6a1a9f8f3d1877e0b47ff706ee37d8d48d6adfad19b0095058d339b",
+		},
+		{
+			name:      "empty secret and payload",
+			timestamp: "1700000000",
+			want:      "c1da1b6c6b8e9da7f4bbb90f7cab0820f271ad19ccbf80c88479c4e14f37d1c6",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Sign(tt.secret, tt.timestamp, tt.payload)
+
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 3, want: 2 * time.Minute},
+		{attempts: MaxAttempts - 1, want: 32 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		got := Backoff(tt.attempts)
+
+		if got != tt.want {
+			t.Errorf("Backoff(%d): got %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
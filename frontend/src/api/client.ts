@@ -20,13 +20,13 @@ export interface Hold {
   id: string
   boardID: string
   vertices: Point[]
-  type?: 'start' | 'hand' | 'foot' | 'finish'
+  type?: 'start' | 'hand' | 'zone' | 'foot' | 'finish'
   createdAt?: string
   updatedAt?: string
   holdID?: string  // Used when the hold is part of a problem
 }
 
-export type ProblemStatus = 'DRAFT' | 'PUBLISHED'
+export type ProblemStatus = 'DRAFT' | 'IN_REVIEW' | 'PUBLISHED' | 'ARCHIVED'
 
 export interface Problem {
   id: string
@@ -82,6 +82,24 @@ const api = axios.create({
   baseURL: API_BASE_URL,
 })
 
+// The API identifies callers by their X-User-ID header. There are no accounts
+// yet, so each browser makes up an ID the first time it's needed and keeps it.
+const USER_ID_KEY = 'bloc.userId'
+
+export const getUserId = () => {
+  let id = localStorage.getItem(USER_ID_KEY)
+  if (!id) {
+    id = crypto.randomUUID()
+    localStorage.setItem(USER_ID_KEY, id)
+  }
+  return id
+}
+
+api.interceptors.request.use((config) => {
+  config.headers.set('X-User-ID', getUserId())
+  return config
+})
+
 // Add response interceptor for debugging
 api.interceptors.response.use(
   (response) => {
@@ -4,7 +4,7 @@ import { Stage, Layer, Image as KonvaImage, Line } from 'react-konva'
 import type { Hold } from '../api/client'
 
 export interface BoardHold extends Hold {
-  type?: 'start' | 'hand' | 'foot' | 'finish'
+  type?: 'start' | 'hand' | 'zone' | 'foot' | 'finish'
   onClick?: () => void
 }
 
@@ -51,6 +51,8 @@ const ProblemDetail = () => {
         return 'rgba(0, 255, 0, 0.5)' // Green
       case 'hand':
         return 'rgba(0, 0, 255, 0.5)' // Blue
+      case 'zone':
+        return 'rgba(255, 165, 0, 0.5)' // Orange
       case 'foot':
         return 'rgba(255, 255, 0, 0.5)' // Yellow
       case 'finish':